@@ -7,7 +7,9 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/labstack/gommon/color"
+	"github.com/maps90/go-core/log"
 	config "github.com/spf13/viper"
 
 	_ "github.com/spf13/viper/remote" // required for remote access
@@ -77,6 +79,7 @@ func (c *Configuration) Configure(p string) error {
 	ext := filepath.Ext(p)
 	ext = strings.TrimPrefix(ext, ".")
 	config.SetConfigType(ext)
+	config.SetConfigFile(AbsolutePath(p))
 
 	file, err := os.Open(AbsolutePath(p))
 	if err != nil {
@@ -90,6 +93,40 @@ func (c *Configuration) Configure(p string) error {
 	return nil
 }
 
+// ConfigureLog applies the "log" section of the config, see log.Config, and
+// keeps the defaults when it is not set
+func (c *Configuration) ConfigureLog() error {
+	if !config.IsSet("log") {
+		return nil
+	}
+
+	cfg, err := log.LoadConfig("log")
+	if err != nil {
+		return err
+	}
+
+	return log.Configure(cfg)
+}
+
+// WatchLog reapplies the levels of the "log" section, see log.ApplyLevels,
+// whenever the local config file changes
+func (c *Configuration) WatchLog() {
+	config.OnConfigChange(func(fsnotify.Event) {
+		if !config.IsSet("log") {
+			return
+		}
+
+		cfg, err := log.LoadConfig("log")
+		if err == nil {
+			err = log.ApplyLevels(cfg)
+		}
+		if err != nil {
+			log.New(log.ErrorLevelLog, "config", "reload log levels: ", err.Error())
+		}
+	})
+	config.WatchConfig()
+}
+
 func AbsolutePath(inPath string) string {
 	if strings.HasPrefix(inPath, "$HOME") {
 		inPath = userHomeDir() + inPath[5:]
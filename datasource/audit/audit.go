@@ -0,0 +1,240 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/log"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+
+	actorKey     = "audit:actor"
+	requestIDKey = "audit:request_id"
+	beforeKey    = "audit:before"
+	afterKey     = "audit:after"
+)
+
+// Table is where audit records are stored
+var Table = "audit_logs"
+
+// Record is a single audited change of a row
+type Record struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	Table     string    `gorm:"column:table_name;size:64;index:idx_audit_record" json:"table"`
+	RecordID  string    `gorm:"size:64;index:idx_audit_record" json:"record_id"`
+	Action    string    `gorm:"size:16" json:"action"`
+	Actor     string    `gorm:"size:255" json:"actor"`
+	RequestID string    `gorm:"size:64" json:"request_id"`
+	Changes   string    `gorm:"type:text" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Record) TableName() string {
+	return Table
+}
+
+// Change holds a column value before and after the change
+type Change struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// Diff decodes the changed columns of the record
+func (r *Record) Diff() (map[string]Change, error) {
+	changes := make(map[string]Change)
+	err := json.Unmarshal([]byte(r.Changes), &changes)
+	return changes, err
+}
+
+// Excluder is implemented by models with columns that must not be audited,
+// e.g. password hashes
+type Excluder interface {
+	AuditExclude() []string
+}
+
+// Register adds audit callbacks to the write connection of ds. Records are
+// written in the transaction of the change, so they are rolled back with it
+// and a failed record fails the change. Changes made without a primary key
+// such as batch updates are not audited.
+func Register(ds datasource.Datasource) {
+	callback := ds.Write().Callback()
+
+	callback.Create().After("gorm:create").Register("audit:after_create", snapshot(afterKey))
+	callback.Create().After("audit:after_create").Register("audit:create", write(ActionCreate))
+
+	callback.Update().Before("gorm:update").Register("audit:before_update", snapshot(beforeKey))
+	callback.Update().After("gorm:update").Register("audit:after_update", snapshot(afterKey))
+	callback.Update().After("audit:after_update").Register("audit:update", write(ActionUpdate))
+
+	callback.Delete().Before("gorm:delete").Register("audit:before_delete", snapshot(beforeKey))
+	callback.Delete().After("gorm:delete").Register("audit:delete", write(ActionDelete))
+}
+
+// WithContext returns a copy of db that records the user and request id
+// found in ctx. The request id is set by middleware.RequestID, the user by
+// middleware.User or log.WithUser, records without a user have an empty
+// actor.
+func WithContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	return db.Set(actorKey, log.User(ctx)).Set(requestIDKey, log.RequestID(ctx))
+}
+
+// History returns the audit records of model, which must have its primary
+// key set, oldest first
+func History(db *gorm.DB, model interface{}) ([]Record, error) {
+	scope := db.NewScope(model)
+	if scope.PrimaryKeyZero() {
+		return nil, fmt.Errorf("audit: %s has no primary key value", scope.TableName())
+	}
+
+	var records []Record
+	err := db.Where("table_name = ? AND record_id = ?", scope.TableName(), fmt.Sprint(scope.PrimaryKeyValue())).
+		Order("id").
+		Find(&records).Error
+
+	return records, err
+}
+
+func skip(scope *gorm.Scope) bool {
+	return scope.HasError() || scope.TableName() == Table || scope.PrimaryKeyZero()
+}
+
+// snapshot loads the current row inside the running transaction
+func snapshot(key string) func(*gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		if skip(scope) {
+			return
+		}
+
+		row, err := load(scope)
+		if err != nil {
+			log.New(log.ErrorLevelLog, "audit", "load ", scope.TableName(), ": ", err.Error())
+			return
+		}
+		scope.InstanceSet(key, row)
+	}
+}
+
+func write(action string) func(*gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		if skip(scope) {
+			return
+		}
+
+		var before, after map[string]interface{}
+		if v, ok := scope.InstanceGet(beforeKey); ok {
+			before = v.(map[string]interface{})
+		}
+		if v, ok := scope.InstanceGet(afterKey); ok {
+			after = v.(map[string]interface{})
+		}
+
+		changes := diff(before, after, excluded(scope.Value))
+		if len(changes) == 0 {
+			return
+		}
+		b, err := json.Marshal(changes)
+		if err != nil {
+			log.New(log.ErrorLevelLog, "audit", "encode changes: ", err.Error())
+			return
+		}
+
+		record := &Record{
+			Table:    scope.TableName(),
+			RecordID: fmt.Sprint(scope.PrimaryKeyValue()),
+			Action:   action,
+			Changes:  string(b),
+		}
+		if v, ok := scope.Get(actorKey); ok {
+			record.Actor, _ = v.(string)
+		}
+		if v, ok := scope.Get(requestIDKey); ok {
+			record.RequestID, _ = v.(string)
+		}
+
+		// NewDB shares the transaction of scope without its conditions
+		if err := scope.NewDB().Create(record).Error; err != nil {
+			scope.Err(fmt.Errorf("audit: write record: %v", err))
+		}
+	}
+}
+
+func load(scope *gorm.Scope) (map[string]interface{}, error) {
+	rows, err := scope.NewDB().
+		Table(scope.TableName()).
+		Where(fmt.Sprintf("%s = ?", scope.Quote(scope.PrimaryKey())), scope.PrimaryKeyValue()).
+		Limit(1).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		v := *(values[i].(*interface{}))
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		row[column] = v
+	}
+
+	return row, nil
+}
+
+func excluded(value interface{}) map[string]bool {
+	skip := make(map[string]bool)
+	if e, ok := value.(Excluder); ok {
+		for _, column := range e.AuditExclude() {
+			skip[column] = true
+		}
+	}
+	return skip
+}
+
+func diff(before, after map[string]interface{}, skip map[string]bool) map[string]Change {
+	changes := make(map[string]Change)
+	for column, old := range before {
+		if skip[column] {
+			continue
+		}
+		if n, ok := after[column]; !ok {
+			changes[column] = Change{Old: old}
+		} else if fmt.Sprint(old) != fmt.Sprint(n) {
+			changes[column] = Change{Old: old, New: n}
+		}
+	}
+	for column, n := range after {
+		if skip[column] {
+			continue
+		}
+		if _, ok := before[column]; !ok {
+			changes[column] = Change{New: n}
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/log"
+)
+
+type account struct {
+	ID       uint
+	Email    string
+	Password string
+}
+
+func (account) AuditExclude() []string {
+	return []string{"password"}
+}
+
+func TestAudit(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+	ds.Write().AutoMigrate(&account{}, &Record{})
+	Register(ds)
+
+	ctx := log.WithRequestID(log.WithUser(context.Background(), "admin"), "req-1")
+	db := WithContext(ds.Write(), ctx)
+
+	a := &account{Email: "a@example.com", Password: "secret"}
+	if err := db.Create(a).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(a).Updates(map[string]interface{}{"email": "b@example.com", "password": "changed"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(a).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := History(ds.Read(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("should record create, update and delete, got %d", len(records))
+	}
+
+	for i, action := range []string{ActionCreate, ActionUpdate, ActionDelete} {
+		r := records[i]
+		if r.Action != action || r.Actor != "admin" || r.RequestID != "req-1" {
+			t.Errorf("unexpected record %+v", r)
+		}
+		changes, err := r.Diff()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := changes["password"]; ok {
+			t.Errorf("%s should not contain excluded column", action)
+		}
+	}
+
+	changes, _ := records[1].Diff()
+	if len(changes) != 1 || changes["email"].Old != "a@example.com" || changes["email"].New != "b@example.com" {
+		t.Errorf("update should only record email change, got %v", changes)
+	}
+}
+
+func TestAuditRollback(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+	ds.Write().AutoMigrate(&account{}, &Record{})
+	Register(ds)
+
+	tx := ds.Write().Begin()
+	a := &account{Email: "a@example.com"}
+	if err := tx.Create(a).Error; err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	tx.Model(&Record{}).Count(&count)
+	if count != 1 {
+		t.Errorf("record should be written in the transaction, got %d", count)
+	}
+	tx.Rollback()
+
+	ds.Read().Model(&Record{}).Count(&count)
+	if count != 0 {
+		t.Errorf("record should be rolled back with the change, got %d", count)
+	}
+
+	ds.Write().DropTable(&Record{})
+	if err := ds.Write().Create(&account{Email: "b@example.com"}).Error; err == nil {
+		t.Error("change should fail when its record cannot be written")
+	}
+	if err := ds.Read().Where("email = ?", "b@example.com").First(&account{}).Error; err == nil {
+		t.Error("failed change should be rolled back")
+	}
+}
@@ -0,0 +1,335 @@
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// limits of parameters per prepared statement, sqlite is built with
+// SQLITE_MAX_VARIABLE_NUMBER 32766
+const (
+	maxPlaceholders       = 65535
+	maxSqlitePlaceholders = 32766
+)
+
+// packetOverhead is kept free in each packet for the protocol header
+const packetOverhead = 1024
+
+type mode int
+
+const (
+	insert mode = iota
+	ignore
+	upsert
+)
+
+// Result reports a single executed batch
+type Result struct {
+	Batch    int
+	Rows     int
+	Affected int64
+}
+
+// Inserter writes slices of gorm models with multi-row INSERT statements.
+// Batches are not wrapped in a transaction, pass a transaction to New when
+// the whole slice must be written atomically.
+type Inserter struct {
+	db        *gorm.DB
+	batchSize int
+	maxPacket int
+}
+
+func New(db *gorm.DB) *Inserter {
+	return &Inserter{
+		db:        db,
+		batchSize: 1000,
+	}
+}
+
+// SetBatchSize sets the maximum rows per statement, defaults to 1000
+func (b *Inserter) SetBatchSize(size int) {
+	b.batchSize = size
+}
+
+// SetMaxPacket sets the maximum statement size in bytes, by default it is
+// read from @@max_allowed_packet on mysql and unlimited otherwise
+func (b *Inserter) SetMaxPacket(size int) {
+	b.maxPacket = size
+}
+
+// Insert writes rows, a slice of structs or struct pointers
+func (b *Inserter) Insert(rows interface{}) ([]Result, error) {
+	return b.exec(rows, insert, nil)
+}
+
+// InsertIgnore writes rows and skips the ones conflicting with an existing
+// unique key
+func (b *Inserter) InsertIgnore(rows interface{}) ([]Result, error) {
+	return b.exec(rows, ignore, nil)
+}
+
+// Upsert writes rows and updates columns of the ones conflicting with an
+// existing unique key, every column but the primary key and created_at is
+// updated when columns is empty. On mysql each updated row counts as 2 affected rows. On sqlite
+// only primary key conflicts are handled.
+func (b *Inserter) Upsert(rows interface{}, columns ...string) ([]Result, error) {
+	return b.exec(rows, upsert, columns)
+}
+
+type statement struct {
+	scope   *gorm.Scope
+	dialect string
+	mode    mode
+	table   string
+	columns []string
+	primary []string
+	update  []string
+}
+
+func (b *Inserter) exec(rows interface{}, m mode, update []string) ([]Result, error) {
+	values := reflect.Indirect(reflect.ValueOf(rows))
+	if values.Kind() != reflect.Slice {
+		return nil, errors.New("bulk: rows must be a slice")
+	}
+	if values.Len() == 0 {
+		return nil, nil
+	}
+
+	first := values.Index(0).Interface()
+	if reflect.Indirect(values.Index(0)).Kind() != reflect.Struct {
+		return nil, errors.New("bulk: rows must be structs")
+	}
+
+	scope := b.db.NewScope(first)
+	st := &statement{
+		scope:   scope,
+		dialect: b.db.Dialect().GetName(),
+		mode:    m,
+		table:   scope.TableName(),
+	}
+
+	for _, f := range scope.PrimaryFields() {
+		st.primary = append(st.primary, f.DBName)
+	}
+	fields := insertFields(scope)
+	if len(fields) == 0 {
+		return nil, errors.New("bulk: no columns to insert")
+	}
+	for _, f := range fields {
+		// an existing row keeps its creation time
+		if !f.IsPrimaryKey && f.Name != "CreatedAt" && len(update) == 0 {
+			st.update = append(st.update, f.DBName)
+		}
+	}
+	if len(update) > 0 {
+		st.update = update
+	}
+	if m == upsert && len(st.update) == 0 {
+		return nil, errors.New("bulk: no columns to update")
+	}
+
+	placeholders := maxPlaceholders
+	if st.dialect == "sqlite3" {
+		placeholders = maxSqlitePlaceholders
+	}
+
+	maxPacket, err := b.packetSize()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results []Result
+		args    []interface{}
+		count   int
+		maxRows int
+		base    int
+		size    int
+		now     = time.Now()
+	)
+
+	flush := func() error {
+		if count == 0 {
+			return nil
+		}
+		res := b.db.Exec(st.sql(count), args...)
+		if res.Error != nil {
+			return fmt.Errorf("bulk: batch %d: %v", len(results), res.Error)
+		}
+		results = append(results, Result{Batch: len(results), Rows: count, Affected: res.RowsAffected})
+		args, count, size = nil, 0, base
+		return nil
+	}
+
+	for i := 0; i < values.Len(); i++ {
+		row := b.db.NewScope(values.Index(i).Interface())
+		fields := insertFields(row)
+		if len(fields) == 0 {
+			return results, errors.New("bulk: no columns to insert")
+		}
+
+		// rows with and without a primary key value insert different
+		// columns, each set gets its own batches
+		if columns := fieldNames(fields); !equal(columns, st.columns) {
+			if err := flush(); err != nil {
+				return results, err
+			}
+			st.columns = columns
+			maxRows = b.batchSize
+			if perStmt := placeholders / len(columns); maxRows <= 0 || maxRows > perStmt {
+				maxRows = perStmt
+			}
+			base = len(st.sql(1))
+			size = base
+		}
+
+		rowArgs := make([]interface{}, 0, len(fields))
+		rowSize := 2 * len(fields)
+		for _, field := range fields {
+			v := field.Field.Interface()
+			if (field.Name == "CreatedAt" || field.Name == "UpdatedAt") && field.IsBlank {
+				field.Set(now)
+				v = now
+			}
+			rowArgs = append(rowArgs, v)
+			rowSize += argSize(v)
+		}
+
+		if count > 0 && (count >= maxRows || (maxPacket > 0 && size+rowSize > maxPacket)) {
+			if err := flush(); err != nil {
+				return results, err
+			}
+		}
+		args = append(args, rowArgs...)
+		count++
+		size += rowSize
+	}
+
+	if err := flush(); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (b *Inserter) packetSize() (int, error) {
+	if b.maxPacket > 0 || b.db.Dialect().GetName() != "mysql" {
+		return b.maxPacket, nil
+	}
+
+	var size int
+	if err := b.db.CommonDB().QueryRow("SELECT @@max_allowed_packet").Scan(&size); err != nil {
+		return 0, err
+	}
+	b.maxPacket = size - packetOverhead
+
+	return b.maxPacket, nil
+}
+
+// insertFields returns the columns gorm would insert, a blank primary key is
+// left out so the database assigns it
+func insertFields(scope *gorm.Scope) []*gorm.Field {
+	var fields []*gorm.Field
+	for _, f := range scope.Fields() {
+		if !f.IsNormal || f.IsIgnored {
+			continue
+		}
+		if f.IsPrimaryKey && f.IsBlank {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func fieldNames(fields []*gorm.Field) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.DBName
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *statement) sql(rows int) string {
+	columns := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		columns[i] = s.scope.Quote(c)
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+
+	verb := "INSERT INTO"
+	if s.mode == ignore {
+		if s.dialect == "sqlite3" {
+			verb = "INSERT OR IGNORE INTO"
+		} else {
+			verb = "INSERT IGNORE INTO"
+		}
+	}
+
+	sql := fmt.Sprintf("%s %s (%s) VALUES %s",
+		verb,
+		s.scope.Quote(s.table),
+		strings.Join(columns, ", "),
+		strings.TrimSuffix(strings.Repeat(placeholders+", ", rows), ", "))
+
+	if s.mode != upsert {
+		return sql
+	}
+
+	set := make([]string, len(s.update))
+	for i, c := range s.update {
+		q := s.scope.Quote(c)
+		if s.dialect == "sqlite3" {
+			set[i] = fmt.Sprintf("%s = excluded.%s", q, q)
+		} else {
+			set[i] = fmt.Sprintf("%s = VALUES(%s)", q, q)
+		}
+	}
+	if s.dialect == "sqlite3" {
+		primary := make([]string, len(s.primary))
+		for i, c := range s.primary {
+			primary[i] = s.scope.Quote(c)
+		}
+		return sql + fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(primary, ", "), strings.Join(set, ", "))
+	}
+
+	return sql + " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+}
+
+// argSize estimates the bytes of an argument in the statement
+func argSize(v interface{}) int {
+	switch val := v.(type) {
+	case nil:
+		return 4
+	case string:
+		return len(val) + 2
+	case []byte:
+		return len(val) + 2
+	case *string:
+		if val == nil {
+			return 4
+		}
+		return len(*val) + 2
+	case time.Time, *time.Time:
+		return 28
+	default:
+		return len(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,172 @@
+package bulk
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maps90/go-core/datasource"
+)
+
+type product struct {
+	ID        uint
+	SKU       string `gorm:"unique_index"`
+	Name      string
+	Stock     int
+	CreatedAt time.Time
+}
+
+func setup(t *testing.T) *datasource.Sqlite {
+	ds := datasource.NewSqlite(":memory:")
+	if err := ds.Write().AutoMigrate(&product{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func products(n int) []product {
+	rows := make([]product, n)
+	for i := range rows {
+		rows[i] = product{SKU: strings.Repeat("x", i+1), Name: "product", Stock: i}
+	}
+	return rows
+}
+
+func TestInsert(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	b := New(ds.Write())
+	b.SetBatchSize(2)
+	results, err := b.Insert(products(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 || results[2].Rows != 1 || results[0].Affected != 2 {
+		t.Errorf("should insert 3 batches of 2, 2 and 1 rows, got %+v", results)
+	}
+
+	var rows []product
+	ds.Read().Order("id").Find(&rows)
+	if len(rows) != 5 || rows[4].Stock != 4 || rows[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+}
+
+func TestMaxPacket(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	rows := products(4)
+	for i := range rows {
+		rows[i].Name = strings.Repeat("n", 100)
+	}
+
+	b := New(ds.Write())
+	b.SetMaxPacket(400)
+	results, err := b.Insert(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) < 2 {
+		t.Errorf("large rows should be split by packet size, got %+v", results)
+	}
+}
+
+func TestInsertIgnoreAndUpsert(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	b := New(ds.Write())
+	b.Insert([]*product{{ID: 1, SKU: "a", Name: "old", Stock: 1}})
+
+	results, err := b.InsertIgnore([]*product{{ID: 1, SKU: "a", Name: "new", Stock: 2}, {ID: 2, SKU: "b", Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Affected != 1 {
+		t.Errorf("conflicting row should be ignored, got %+v", results)
+	}
+
+	if _, err := b.Upsert([]product{{ID: 1, SKU: "a", Name: "new", Stock: 5}}, "stock"); err != nil {
+		t.Fatal(err)
+	}
+
+	var p product
+	ds.Read().First(&p, 1)
+	if p.Stock != 5 || p.Name != "old" {
+		t.Errorf("upsert should only update stock, got %+v", p)
+	}
+
+	if _, err := b.Insert("nope"); err == nil {
+		t.Error("non slice rows should fail")
+	}
+}
+
+func TestUpsertKeepsCreatedAt(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := New(ds.Write())
+	b.Insert([]product{{ID: 1, SKU: "a", Name: "old", CreatedAt: created}})
+
+	if _, err := b.Upsert([]product{{ID: 1, SKU: "a", Name: "new", Stock: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var p product
+	ds.Read().First(&p, 1)
+	if p.Name != "new" || p.Stock != 5 {
+		t.Errorf("upsert should update the other columns, got %+v", p)
+	}
+	if !p.CreatedAt.Equal(created) {
+		t.Errorf("upsert should keep created_at %v, got %v", created, p.CreatedAt)
+	}
+}
+
+func TestMaxPlaceholders(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	// 4 columns per row, a single mysql sized batch exceeds the sqlite limit
+	b := New(ds.Write())
+	b.SetBatchSize(0)
+	results, err := b.Insert(products(9000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Rows != maxSqlitePlaceholders/4 {
+		t.Errorf("sqlite batches should stay under its parameter limit, got %+v", results)
+	}
+}
+
+func TestInsertMixedPrimaryKeys(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	rows := []product{
+		{SKU: "a", Name: "a", Stock: 1},
+		{ID: 10, SKU: "b", Name: "b", Stock: 2},
+		{ID: 11, SKU: "c", Name: "c", Stock: 3},
+		{SKU: "d", Name: "d", Stock: 4},
+	}
+	results, err := New(ds.Write()).Insert(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[1].Rows != 2 {
+		t.Errorf("rows with and without ids should be inserted in separate batches, got %+v", results)
+	}
+
+	for _, want := range rows {
+		var p product
+		if err := ds.Read().Where("sku = ?", want.SKU).First(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != want.Name || p.Stock != want.Stock || (want.ID != 0 && p.ID != want.ID) {
+			t.Errorf("row %s was bound wrong, got %+v", want.SKU, p)
+		}
+	}
+}
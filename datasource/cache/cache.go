@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache stores values with a TTL, tags group keys so they can be dropped
+// together
+type Cache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration, tags ...string) error
+	Delete(keys ...string) error
+	InvalidateTags(tags ...string) error
+}
+
+// QueryCache caches gorm query results keyed by table, conditions and
+// arguments. Results are tagged with the table name, Register drops them on
+// every write to that table through the datasource.
+type QueryCache struct {
+	cache  Cache
+	ttl    time.Duration
+	prefix string
+	group  singleflight.Group
+}
+
+func New(c Cache) *QueryCache {
+	return &QueryCache{
+		cache:  c,
+		ttl:    time.Minute,
+		prefix: "query:",
+	}
+}
+
+// SetTTL sets how long results are kept, defaults to 1m
+func (q *QueryCache) SetTTL(ttl time.Duration) {
+	q.ttl = ttl
+}
+
+// SetPrefix sets the prefix of cache keys, defaults to "query:"
+func (q *QueryCache) SetPrefix(prefix string) {
+	q.prefix = prefix
+}
+
+// Register invalidates cached results of a table after each create, update
+// and delete made through the write connection of ds. Writes in a
+// transaction begun with Begin invalidate when it is committed with Commit,
+// writes in other transactions invalidate before their commit, so a
+// concurrent Find may cache the previous rows again until the TTL.
+func (q *QueryCache) Register(ds datasource.Datasource) {
+	callback := ds.Write().Callback()
+	callback.Create().After("gorm:commit_or_rollback_transaction").Register("cache:invalidate_create", q.invalidate)
+	callback.Update().After("gorm:commit_or_rollback_transaction").Register("cache:invalidate_update", q.invalidate)
+	callback.Delete().After("gorm:commit_or_rollback_transaction").Register("cache:invalidate_delete", q.invalidate)
+}
+
+// Find works like db.Find(dest) and serves the result from the cache when
+// possible, concurrent misses of the same query run it once. Results are
+// stored as json so dest fields must round trip through encoding/json.
+//
+//	err := qc.Find(mysql.Read().Where("status = ?", "active"), &products)
+func (q *QueryCache) Find(db *gorm.DB, dest interface{}, tags ...string) error {
+	// uncommitted rows are not cached
+	if _, ok := db.Get(transactionKey); ok {
+		return db.Find(dest).Error
+	}
+
+	key, table := q.key(db, dest)
+
+	b, ok, err := q.cache.Get(key)
+	if err != nil {
+		log.NewWithFields(log.WarnLevelLog, "cache", log.Fields{"key": key}, "get: ", err.Error())
+	}
+	if ok {
+		return json.Unmarshal(b, dest)
+	}
+
+	v, err, _ := q.group.Do(key, func() (interface{}, error) {
+		tags := append(append([]string(nil), tags...), table)
+		before, genErr := q.generations(tags)
+
+		rows := reflect.New(reflect.TypeOf(dest).Elem())
+		if err := db.Find(rows.Interface()).Error; err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(rows.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		// a write invalidating the tags during the load may not be in rows
+		after, err := q.generations(tags)
+		if genErr != nil || err != nil || before != after {
+			return b, nil
+		}
+		if err := q.cache.Set(key, b, q.ttl, tags...); err != nil {
+			log.NewWithFields(log.WarnLevelLog, "cache", log.Fields{"key": key}, "set: ", err.Error())
+		}
+		return b, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(v.([]byte), dest)
+}
+
+// Invalidate drops every result tagged with tags, table names included
+func (q *QueryCache) Invalidate(tags ...string) error {
+	if err := q.bump(tags); err != nil {
+		return err
+	}
+	return q.cache.InvalidateTags(tags...)
+}
+
+// Begin starts a transaction on db whose writes invalidate the cached
+// results once it is committed with Commit, Find runs uncached on it, e.g.
+//
+//	tx := qc.Begin(mysql.Write())
+//	tx.Create(&order)
+//	err := qc.Commit(tx)
+func (q *QueryCache) Begin(db *gorm.DB) *gorm.DB {
+	return db.Set(transactionKey, &transaction{tables: make(map[string]struct{})}).Begin()
+}
+
+// Commit commits tx, begun with Begin, and invalidates the tables it wrote
+func (q *QueryCache) Commit(tx *gorm.DB) error {
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	v, ok := tx.Get(transactionKey)
+	if !ok {
+		return nil
+	}
+	t := v.(*transaction)
+	t.mu.Lock()
+	tables := make([]string, 0, len(t.tables))
+	for table := range t.tables {
+		tables = append(tables, table)
+	}
+	t.mu.Unlock()
+
+	if len(tables) == 0 {
+		return nil
+	}
+	return q.Invalidate(tables...)
+}
+
+const transactionKey = "cache:transaction"
+
+// transaction collects the tables written in a transaction begun with Begin
+type transaction struct {
+	mu     sync.Mutex
+	tables map[string]struct{}
+}
+
+func (q *QueryCache) invalidate(scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+	if v, ok := scope.Get(transactionKey); ok {
+		t := v.(*transaction)
+		t.mu.Lock()
+		t.tables[scope.TableName()] = struct{}{}
+		t.mu.Unlock()
+		return
+	}
+	if err := q.Invalidate(scope.TableName()); err != nil {
+		log.NewWithFields(log.ErrorLevelLog, "cache", log.Fields{"table": scope.TableName()}, "invalidate: ", err.Error())
+	}
+}
+
+// generations returns the generations of tags, each invalidation starts a
+// new one so Find does not cache a result loaded across it
+func (q *QueryCache) generations(tags []string) (string, error) {
+	var gens []string
+	for _, tag := range tags {
+		b, _, err := q.cache.Get(q.generationKey(tag))
+		if err != nil {
+			return "", err
+		}
+		gens = append(gens, string(b))
+	}
+	return strings.Join(gens, "|"), nil
+}
+
+func (q *QueryCache) bump(tags []string) error {
+	b := make([]byte, 8)
+	rand.Read(b)
+	gen := []byte(hex.EncodeToString(b))
+	for _, tag := range tags {
+		if err := q.cache.Set(q.generationKey(tag), gen, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *QueryCache) generationKey(tag string) string {
+	return q.prefix + "generation:" + tag
+}
+
+// key hashes the parts of the query that change its result
+func (q *QueryCache) key(db *gorm.DB, dest interface{}) (string, string) {
+	scope := db.NewScope(dest)
+	table := scope.TableName()
+	conditions := scope.CombinedConditionSql()
+
+	option, _ := scope.Get("gorm:query_option")
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%T|%v",
+		table,
+		strings.Join(scope.SelectAttrs(), ","),
+		conditions,
+		dest,
+		option)
+	for _, v := range scope.SQLVars {
+		fmt.Fprintf(h, "|%s", value(reflect.ValueOf(v)))
+	}
+
+	// gorm keeps the preloads unexported
+	preload := reflect.ValueOf(scope.Search).Elem().FieldByName("preload")
+	for i := 0; i < preload.Len(); i++ {
+		p := preload.Index(i)
+		fmt.Fprintf(h, "|preload %s", p.FieldByName("schema").String())
+		conditions := p.FieldByName("conditions")
+		for j := 0; j < conditions.Len(); j++ {
+			fmt.Fprintf(h, "|%s", value(conditions.Index(j)))
+		}
+	}
+
+	return q.prefix + table + ":" + hex.EncodeToString(h.Sum(nil)), table
+}
+
+// value formats a query argument, pointers are followed so equal arguments
+// give equal keys
+func value(v reflect.Value) string {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	if v.CanInterface() {
+		return fmt.Sprintf("%T:%v", v.Interface(), v.Interface())
+	}
+	return v.Type().String() + ":" + fmt.Sprint(v)
+}
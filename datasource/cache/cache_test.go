@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis"
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+)
+
+type product struct {
+	ID   uint
+	Name string
+}
+
+func testCache(t *testing.T, c Cache) {
+	c.Set("a", []byte("1"), time.Minute, "products")
+	c.Set("b", []byte("2"), time.Minute, "products", "orders")
+	c.Set("c", []byte("3"), 0)
+
+	if v, ok, err := c.Get("a"); string(v) != "1" || !ok || err != nil {
+		t.Errorf("a should be cached, got %s %v %v", v, ok, err)
+	}
+	if _, ok, _ := c.Get("missing"); ok {
+		t.Error("missing key should not be found")
+	}
+
+	c.InvalidateTags("orders")
+	if _, ok, _ := c.Get("b"); ok {
+		t.Error("b should be invalidated by its tag")
+	}
+	if _, ok, _ := c.Get("a"); !ok {
+		t.Error("a should survive invalidation of another tag")
+	}
+
+	c.Delete("a", "c")
+	if _, ok, _ := c.Get("c"); ok {
+		t.Error("c should be deleted")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	testCache(t, NewLRU(10))
+
+	c := NewLRU(2)
+	c.Set("a", nil, 0)
+	c.Set("b", nil, 0)
+	c.Get("a")
+	c.Set("c", nil, 0)
+	if _, ok, _ := c.Get("b"); ok || c.Len() != 2 {
+		t.Error("least recently used entry should be evicted")
+	}
+
+	c.Set("d", nil, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	if _, ok, _ := c.Get("d"); ok {
+		t.Error("expired entry should not be found")
+	}
+}
+
+func TestRedis(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c := NewRedis(redis.NewClient(&redis.Options{Addr: s.Addr()}))
+	testCache(t, c)
+
+	c.Set("e", []byte("5"), time.Second)
+	s.FastForward(2 * time.Second)
+	if _, ok, _ := c.Get("e"); ok {
+		t.Error("expired key should not be found")
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+	db := ds.Write()
+	db.AutoMigrate(&product{})
+	db.Create(&product{Name: "apple"})
+	db.Create(&product{Name: "pear"})
+
+	// slow queries down so concurrent misses overlap
+	var queries int32
+	db.Callback().Query().Before("gorm:query").Register("test:count", func(*gorm.Scope) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	qc := New(NewLRU(100))
+	qc.Register(ds)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var products []product
+			if err := qc.Find(db.Where("name <> ?", "kiwi"), &products); err != nil || len(products) != 2 {
+				t.Errorf("unexpected result %v %v", products, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Errorf("identical queries should run once, ran %d", n)
+	}
+
+	var pears []product
+	qc.Find(db.Where("name = ?", "pear"), &pears)
+	if len(pears) != 1 || atomic.LoadInt32(&queries) != 2 {
+		t.Errorf("different arguments should not share a key, got %v", pears)
+	}
+
+	db.Create(&product{Name: "plum"})
+
+	var products []product
+	qc.Find(db.Where("name <> ?", "kiwi"), &products)
+	if len(products) != 3 {
+		t.Errorf("writes should invalidate cached results, got %v", products)
+	}
+}
+
+func TestQueryCacheWriteDuringLoad(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+	db := ds.Write()
+	db.AutoMigrate(&product{})
+	db.Create(&product{Name: "apple"})
+
+	qc := New(NewLRU(100))
+	qc.Register(ds)
+
+	var queries int32
+	db.Callback().Query().After("gorm:query").Register("test:write", func(*gorm.Scope) {
+		// a concurrent write lands after the rows were read
+		if atomic.AddInt32(&queries, 1) == 1 {
+			db.Create(&product{Name: "pear"})
+		}
+	})
+
+	var products []product
+	qc.Find(db, &products)
+	if len(products) != 1 {
+		t.Fatalf("first load should read the rows before the write, got %v", products)
+	}
+	qc.Find(db, &products)
+	if len(products) != 2 {
+		t.Errorf("result loaded across a write should not be cached, got %v", products)
+	}
+}
+
+func TestQueryCacheTransaction(t *testing.T) {
+	// readers outside the transaction need their own connection
+	ds := datasource.NewSqlite(filepath.Join(t.TempDir(), "cache.db"))
+	defer ds.Close()
+	db := ds.Write()
+	db.AutoMigrate(&product{})
+	db.Create(&product{Name: "apple"})
+
+	qc := New(NewLRU(100))
+	qc.Register(ds)
+
+	tx := qc.Begin(db)
+	if err := tx.Create(&product{Name: "pear"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var inside []product
+	qc.Find(tx, &inside)
+	if len(inside) != 2 {
+		t.Errorf("the transaction should read its own rows, got %v", inside)
+	}
+
+	// a reader caches the committed rows before the commit
+	var products []product
+	qc.Find(db, &products)
+	if len(products) != 1 {
+		t.Fatalf("uncommitted rows should not be read, got %v", products)
+	}
+
+	if err := qc.Commit(tx); err != nil {
+		t.Fatal(err)
+	}
+	qc.Find(db, &products)
+	if len(products) != 2 {
+		t.Errorf("commit should invalidate the rows cached meanwhile, got %v", products)
+	}
+}
+
+func TestQueryCacheKey(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+	db := ds.Write()
+	qc := New(NewLRU(100))
+
+	a, b := "apple", "apple"
+	keyA, _ := qc.key(db.Where("name = ?", &a), &[]product{})
+	keyB, _ := qc.key(db.Where("name = ?", &b), &[]product{})
+	if keyA != keyB {
+		t.Error("pointer arguments should be keyed by their value")
+	}
+
+	plain, _ := qc.key(db, &[]product{})
+	preloaded, _ := qc.key(db.Preload("Parts"), &[]product{})
+	if plain == preloaded {
+		t.Error("preloads should change the key")
+	}
+
+	tags := make([]string, 1, 2)
+	tags[0] = "shared"
+	var products []product
+	db.AutoMigrate(&product{})
+	qc.Find(db, &products, tags...)
+	if extra := tags[:2][1]; extra != "" {
+		t.Errorf("Find should not write to the tags of the caller, got %q", extra)
+	}
+}
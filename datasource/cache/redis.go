@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Redis is a Cache shared by every instance using the same server. Tags are
+// sets of keys, expired keys are left in them until the tag is invalidated
+// or the set itself expires.
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{
+		client: client,
+		prefix: "gocore:",
+	}
+}
+
+// SetPrefix sets the prefix of every redis key, defaults to "gocore:"
+func (c *Redis) SetPrefix(prefix string) {
+	c.prefix = prefix
+}
+
+func (c *Redis) Get(key string) ([]byte, bool, error) {
+	b, err := c.client.Get(c.prefix + key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set stores value, a zero ttl never expires
+func (c *Redis) Set(key string, value []byte, ttl time.Duration, tags ...string) error {
+	pipe := c.client.TxPipeline()
+	pipe.Set(c.prefix+key, value, ttl)
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+		pipe.SAdd(tagKey, c.prefix+key)
+		if ttl > 0 {
+			// the tag expires with the last key added, so use one ttl per tag
+			pipe.Expire(tagKey, ttl)
+		} else {
+			pipe.Persist(tagKey)
+		}
+	}
+	_, err := pipe.Exec()
+
+	return err
+}
+
+func (c *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.client.Del(prefixed...).Err()
+}
+
+func (c *Redis) InvalidateTags(tags ...string) error {
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+		keys, err := c.client.SMembers(tagKey).Result()
+		if err != nil {
+			return err
+		}
+		if err := c.client.Del(append(keys, tagKey)...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Redis) tagKey(tag string) string {
+	return c.prefix + "tag:" + tag
+}
@@ -0,0 +1,242 @@
+package datasource
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/maps90/go-core/validation"
+	config "github.com/spf13/viper"
+)
+
+const redactedPassword = "xxxxx"
+
+// MysqlConfig describes a mysql connection and its pool, e.g.
+//
+//	database:
+//	  write:
+//	    host: 10.0.0.1
+//	    user: app
+//	    password: secret
+//	    database: orders
+//	    parse_time: true
+//	    loc: Asia/Jakarta
+//	    timeout: 5s
+//	    params:
+//	      sql_mode: "'STRICT_ALL_TABLES'"
+//	    max_open_conns: 20
+//	    conn_max_lifetime: 1h
+type MysqlConfig struct {
+	Host      string            `mapstructure:"host" json:"host" valid:"Required"`
+	Port      int               `mapstructure:"port" json:"port" valid:"Range(1, 65535)"`
+	User      string            `mapstructure:"user" json:"user" valid:"Required"`
+	Password  string            `mapstructure:"password" json:"password"`
+	Database  string            `mapstructure:"database" json:"database" valid:"Required"`
+	Charset   string            `mapstructure:"charset" json:"charset"`
+	ParseTime bool              `mapstructure:"parse_time" json:"parse_time"`
+	Loc       string            `mapstructure:"loc" json:"loc"`
+	Params    map[string]string `mapstructure:"params" json:"params"`
+
+	// TLS is "true", "false", "skip-verify", "preferred" or empty, setting
+	// TLSCA or TLSCert registers a custom tls config instead
+	TLS           string `mapstructure:"tls" json:"tls" valid:"Match(/^(true|false|skip-verify|preferred)$/)"`
+	TLSCA         string `mapstructure:"tls_ca" json:"tls_ca"`
+	TLSCert       string `mapstructure:"tls_cert" json:"tls_cert"`
+	TLSKey        string `mapstructure:"tls_key" json:"tls_key"`
+	TLSServerName string `mapstructure:"tls_server_name" json:"tls_server_name"`
+
+	Timeout      time.Duration `mapstructure:"timeout" json:"timeout"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
+	// QueryTimeout is the default timeout of ReadCtx and WriteCtx
+	QueryTimeout time.Duration `mapstructure:"query_timeout" json:"query_timeout"`
+
+	MaxOpenConns    int           `mapstructure:"max_open_conns" json:"max_open_conns" valid:"Min(0)"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" json:"max_idle_conns" valid:"Min(0)"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" json:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" json:"conn_max_idle_time"`
+}
+
+// LoadMysqlConfig reads and validates the config section at key
+func LoadMysqlConfig(key string) (*MysqlConfig, error) {
+	if !config.IsSet(key) {
+		return nil, fmt.Errorf("mysql config: %s is not set", key)
+	}
+
+	c := new(MysqlConfig)
+	if err := config.UnmarshalKey(key, c); err != nil {
+		return nil, fmt.Errorf("mysql config: %v", err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate fills in defaults and checks required fields
+func (c *MysqlConfig) Validate() error {
+	if c.Port == 0 {
+		c.Port = 3306
+	}
+	if c.Charset == "" {
+		c.Charset = "utf8mb4"
+	}
+
+	valid := validation.Validation{}
+	if _, err := valid.Valid(c); err != nil {
+		return fmt.Errorf("mysql config: %v", err)
+	}
+	if valid.HasErrors() {
+		msgs := make([]string, 0, len(valid.Errors))
+		for _, e := range valid.Errors {
+			msgs = append(msgs, e.Key+" "+e.Message)
+		}
+		return fmt.Errorf("mysql config: %s", strings.Join(msgs, ", "))
+	}
+
+	if c.Loc != "" {
+		if _, err := time.LoadLocation(c.Loc); err != nil {
+			return fmt.Errorf("mysql config: loc %v", err)
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("mysql config: tls_cert and tls_key must be set together")
+	}
+
+	return nil
+}
+
+// DSN builds the go-sql-driver data source name, values are escaped by the
+// driver so passwords and params may contain any character
+func (c *MysqlConfig) DSN() (string, error) {
+	cfg, err := c.driverConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.FormatDSN(), nil
+}
+
+// String returns the DSN with the password masked, safe to log. It is built
+// from the fields only, the tls files are neither read nor registered.
+func (c *MysqlConfig) String() string {
+	cfg := c.baseConfig()
+	if cfg.Passwd != "" {
+		cfg.Passwd = redactedPassword
+	}
+	if c.Loc != "" {
+		cfg.Params["loc"] = c.Loc
+	}
+	if c.TLSCA != "" || c.TLSCert != "" {
+		cfg.TLSConfig = c.tlsName()
+	}
+
+	return cfg.FormatDSN()
+}
+
+// baseConfig returns the driver config of the fields that need no lookup,
+// the location and custom tls config are set by driverConfig
+func (c *MysqlConfig) baseConfig() *mysql.Config {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = c.Host + ":" + strconv.Itoa(c.Port)
+	cfg.DBName = c.Database
+	cfg.ParseTime = c.ParseTime
+	cfg.Timeout = c.Timeout
+	cfg.ReadTimeout = c.ReadTimeout
+	cfg.WriteTimeout = c.WriteTimeout
+	cfg.TLSConfig = c.TLS
+
+	cfg.Params = make(map[string]string, len(c.Params)+2)
+	for k, v := range c.Params {
+		cfg.Params[k] = v
+	}
+	if c.Charset != "" {
+		cfg.Params["charset"] = c.Charset
+	}
+
+	return cfg
+}
+
+func (c *MysqlConfig) driverConfig() (*mysql.Config, error) {
+	cfg := c.baseConfig()
+
+	if c.Loc != "" {
+		loc, err := time.LoadLocation(c.Loc)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Loc = loc
+	}
+
+	if c.TLSCA != "" || c.TLSCert != "" {
+		name, err := c.registerTLS()
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLSConfig = name
+	}
+
+	return cfg, nil
+}
+
+// tlsName is the name the custom tls config is registered under
+func (c *MysqlConfig) tlsName() string {
+	return fmt.Sprintf("gocore-%s-%d-%s", c.Host, c.Port, c.User)
+}
+
+func (c *MysqlConfig) registerTLS() (string, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLS == "skip-verify",
+	}
+
+	if c.TLSCA != "" {
+		pem, err := ioutil.ReadFile(c.TLSCA)
+		if err != nil {
+			return "", err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("mysql config: no certificate found in %s", c.TLSCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+		if err != nil {
+			return "", err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	name := c.tlsName()
+	if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// RedactDSN masks the password of a go-sql-driver DSN
+func RedactDSN(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "invalid dsn"
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = redactedPassword
+	}
+
+	return cfg.FormatDSN()
+}
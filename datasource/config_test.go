@@ -0,0 +1,88 @@
+package datasource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestMysqlConfigDSN(t *testing.T) {
+	c := &MysqlConfig{
+		Host:      "db.local",
+		User:      "app",
+		Password:  "p@ss/w:rd?",
+		Database:  "orders",
+		ParseTime: true,
+		Loc:       "Asia/Jakarta",
+		Params:    map[string]string{"sql_mode": "'STRICT_ALL_TABLES'"},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	dsn, err := c.DSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Passwd != c.Password {
+		t.Errorf("password should survive escaping, got %q", parsed.Passwd)
+	}
+	if parsed.Addr != "db.local:3306" {
+		t.Errorf("port should default to 3306, got %q", parsed.Addr)
+	}
+	if !strings.Contains(dsn, "charset=utf8mb4") || parsed.Params["sql_mode"] != "'STRICT_ALL_TABLES'" {
+		t.Errorf("unexpected params %s", dsn)
+	}
+	if !parsed.ParseTime || parsed.Loc.String() != "Asia/Jakarta" {
+		t.Error("parseTime and loc should be set")
+	}
+
+	if strings.Contains(c.String(), "p@ss") || !strings.Contains(c.String(), redactedPassword) {
+		t.Errorf("String should redact password, got %s", c.String())
+	}
+	if strings.Contains(RedactDSN(dsn), "p@ss") {
+		t.Error("RedactDSN should mask password")
+	}
+}
+
+func TestMysqlConfigValidate(t *testing.T) {
+	c := &MysqlConfig{Host: "db.local", Port: 70000, MaxOpenConns: -1, TLS: "sometimes"}
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("invalid config should fail")
+	}
+	for _, key := range []string{"user", "database", "port", "max_open_conns", "tls"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error should mention %s: %v", key, err)
+		}
+	}
+}
+
+func TestMysqlConfigStringNoSideEffects(t *testing.T) {
+	c := &MysqlConfig{
+		Host:     "db.local",
+		Port:     3306,
+		User:     "app",
+		Password: "secret",
+		Database: "orders",
+		Loc:      "Asia/Jakarta",
+		TLSCA:    "/nonexistent/ca.pem",
+	}
+
+	s := c.String()
+	if !strings.Contains(s, "tls=gocore-db.local-3306-app") || !strings.Contains(s, "loc=Asia%2FJakarta") {
+		t.Errorf("String should describe tls and loc from the fields, got %s", s)
+	}
+	if strings.Contains(s, "secret") {
+		t.Errorf("String should redact password, got %s", s)
+	}
+	if _, err := c.DSN(); err == nil {
+		t.Error("DSN should fail to read the missing ca file")
+	}
+}
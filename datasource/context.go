@@ -0,0 +1,175 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	contextKey   = "gocore:context"
+	deadlineKey  = "gocore:deadline"
+	statementKey = "gocore:statement"
+)
+
+type timeoutKey struct{}
+
+// ErrQueryTimeout is returned by handles of ReadCtx and WriteCtx when the
+// query timeout expires
+var ErrQueryTimeout = QueryTimeoutError{}
+
+// QueryTimeoutError is the type of ErrQueryTimeout
+type QueryTimeoutError struct{}
+
+func (QueryTimeoutError) Error() string {
+	return "datasource: query timeout"
+}
+
+// StatusCode is used by middleware.ErrorHandler to respond 504 Gateway Timeout
+func (QueryTimeoutError) StatusCode() int {
+	return http.StatusGatewayTimeout
+}
+
+// WithTimeout overrides the default query timeout of ReadCtx and WriteCtx
+// calls made with the returned context, zero disables it
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// withContext returns a copy of db whose statements run with ctx and the
+// query timeout counted from now. The handle holds no connection, each
+// statement takes one from the pool as usual.
+func withContext(ctx context.Context, db *gorm.DB, timeout time.Duration) *gorm.DB {
+	if t, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		timeout = t
+	}
+
+	db = db.Set(contextKey, ctx)
+	if timeout > 0 {
+		db = db.Set(deadlineKey, time.Now().Add(timeout))
+	}
+
+	return db
+}
+
+// statements holds the context of running statements by id, the id travels
+// to contextDriver in a comment appended through the gorm:*_option settings
+// since gorm v1 does not pass a context to database/sql
+var statements struct {
+	sync.Map
+	next int64
+}
+
+var tagPattern = regexp.MustCompile(` ?/\* gocore:ctx (\d+) \*/`)
+
+type statement struct {
+	id     int64
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// registerContextCallbacks binds the statements of ReadCtx and WriteCtx
+// handles to their context and replaces the errors it causes with
+// ErrQueryTimeout or context.Canceled
+func registerContextCallbacks(db *gorm.DB) {
+	callback := db.Callback()
+	callback.Query().Before("gorm:query").Register("gocore:context", bindContext("gorm:query_option"))
+	callback.Query().After("gorm:query").Register("gocore:context_error", releaseContext(true))
+	// rows are read after the callbacks, they are released by the deadline
+	callback.RowQuery().Before("gorm:row_query").Register("gocore:context", bindContext("gorm:query_option"))
+	callback.RowQuery().After("gorm:row_query").Register("gocore:context_error", releaseContext(false))
+	callback.Create().Before("gorm:create").Register("gocore:context", bindContext("gorm:insert_option"))
+	callback.Create().After("gorm:create").Register("gocore:context_error", releaseContext(true))
+	callback.Update().Before("gorm:update").Register("gocore:context", bindContext("gorm:update_option"))
+	callback.Update().After("gorm:update").Register("gocore:context_error", releaseContext(true))
+	callback.Delete().Before("gorm:delete").Register("gocore:context", bindContext("gorm:delete_option"))
+	callback.Delete().After("gorm:delete").Register("gocore:context_error", releaseContext(true))
+}
+
+func bindContext(option string) func(*gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		v, ok := scope.Get(contextKey)
+		if !ok || scope.HasError() {
+			return
+		}
+
+		st := &statement{ctx: v.(context.Context)}
+		if deadline, ok := scope.Get(deadlineKey); ok {
+			st.ctx, st.cancel = context.WithDeadline(st.ctx, deadline.(time.Time))
+		}
+		if err := contextErr(st.ctx); err != nil {
+			// the statement is skipped, gorm does not run it on error
+			if st.cancel != nil {
+				st.cancel()
+			}
+			scope.Err(err)
+			return
+		}
+
+		st.id = atomic.AddInt64(&statements.next, 1)
+		statements.Store(st.id, st.ctx)
+		scope.InstanceSet(statementKey, st)
+
+		// nested statements copy the settings of their parent, its tag is
+		// replaced
+		var s string
+		if v, ok := scope.Get(option); ok {
+			s = tagPattern.ReplaceAllString(fmt.Sprint(v), "")
+		}
+		scope.Set(option, strings.TrimSpace(fmt.Sprintf("%s /* gocore:ctx %d */", s, st.id)))
+	}
+}
+
+func releaseContext(cancel bool) func(*gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		v, ok := scope.InstanceGet(statementKey)
+		if !ok {
+			return
+		}
+		st := v.(*statement)
+		statements.Delete(st.id)
+
+		if scope.HasError() {
+			if err := contextErr(st.ctx); err != nil {
+				scope.DB().Error = err
+			}
+		}
+		if cancel && st.cancel != nil {
+			st.cancel()
+		}
+	}
+}
+
+func contextErr(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return ErrQueryTimeout
+	case context.Canceled:
+		return context.Canceled
+	}
+	return nil
+}
+
+// untag removes the statement tag from query and returns the context of
+// the statement, or nil when query is not tagged
+func untag(query string) (string, context.Context) {
+	m := tagPattern.FindStringSubmatchIndex(query)
+	if m == nil {
+		return query, nil
+	}
+
+	id, _ := strconv.ParseInt(query[m[2]:m[3]], 10, 64)
+	query = query[:m[0]] + query[m[1]:]
+	if v, ok := statements.Load(id); ok {
+		return query, v.(context.Context)
+	}
+	return query, nil
+}
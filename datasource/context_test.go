@@ -0,0 +1,106 @@
+package datasource
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slowQuery counts for several seconds on sqlite
+const slowQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000) SELECT count(*) AS n FROM c"
+
+func TestContext(t *testing.T) {
+	ds := NewSqlite(":memory:")
+	defer ds.Close()
+	ds.Write().AutoMigrate(&note{})
+	ds.SetQueryTimeout(time.Second)
+
+	if err := ds.WriteCtx(context.Background()).Create(&note{Body: "hello"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var notes []note
+	if err := ds.ReadCtx(context.Background()).Find(&notes).Error; err != nil || len(notes) != 1 {
+		t.Errorf("should read written note, got %v %v", notes, err)
+	}
+
+	ctx := WithTimeout(context.Background(), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if err := ds.ReadCtx(ctx).Find(&notes).Error; err != ErrQueryTimeout {
+		t.Errorf("expired timeout should return ErrQueryTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	db := ds.ReadCtx(ctx)
+	cancel()
+	if err := db.Find(&notes).Error; err != context.Canceled {
+		t.Errorf("cancelled request should return context.Canceled, got %v", err)
+	}
+	if err := db.Create(&note{Body: "lost"}).Error; err != context.Canceled {
+		t.Errorf("cancelled request should not write, got %v", err)
+	}
+
+	var count int
+	ds.Read().Model(&note{}).Count(&count)
+	if count != 1 {
+		t.Errorf("cancelled write should not be run, got %d notes", count)
+	}
+
+	// handles do not hold connections
+	for i := 0; i < 10; i++ {
+		ds.ReadCtx(context.Background()).Find(&notes)
+	}
+	if inUse := ds.Write().DB().Stats().InUse; inUse != 0 {
+		t.Errorf("handles should return their connections, %d in use", inUse)
+	}
+}
+
+func TestContextSlowQuery(t *testing.T) {
+	ds := NewSqlite(":memory:")
+	defer ds.Close()
+
+	var out struct{ N int }
+	start := time.Now()
+	err := ds.ReadCtx(WithTimeout(context.Background(), 50*time.Millisecond)).Raw(slowQuery).Scan(&out).Error
+	if err != ErrQueryTimeout {
+		t.Errorf("slow query should return ErrQueryTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("slow query should be aborted at the timeout, took %v", elapsed)
+	}
+
+	stats := ds.Write().DB().Stats()
+	if stats.InUse != 0 || stats.Idle != 1 {
+		t.Errorf("connection should go back to the pool, got %d in use and %d idle", stats.InUse, stats.Idle)
+	}
+	if err := ds.ReadCtx(context.Background()).Raw("SELECT 1 AS n").Scan(&out).Error; err != nil || out.N != 1 {
+		t.Errorf("pooled connection should still work, got %d %v", out.N, err)
+	}
+}
+
+func TestExecutionTimeHint(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT * FROM notes":           "SELECT /*+ MAX_EXECUTION_TIME(1500) */ * FROM notes",
+		"  (select 1) UNION (SELECT 2)": "  (select /*+ MAX_EXECUTION_TIME(1500) */ 1) UNION (SELECT 2)",
+		"UPDATE notes SET body = ''":    "UPDATE notes SET body = ''",
+		"SELECTED":                      "SELECTED",
+	} {
+		if got := executionTimeHint(query, 1500*time.Millisecond); got != want {
+			t.Errorf("executionTimeHint(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestUntag(t *testing.T) {
+	ctx := context.WithValue(context.Background(), timeoutKey{}, 1)
+	statements.Store(int64(1<<62), ctx)
+	defer statements.Delete(int64(1 << 62))
+
+	query, got := untag("SELECT 1 FOR UPDATE /* request_id=req-1 */ /* gocore:ctx 4611686018427387904 */")
+	if query != "SELECT 1 FOR UPDATE /* request_id=req-1 */" || got != ctx {
+		t.Errorf("tag should be removed and resolved, got %q %v", query, got)
+	}
+	if query, got := untag("SELECT 1"); query != "SELECT 1" || got != nil {
+		t.Errorf("untagged query should be unchanged, got %q %v", query, got)
+	}
+}
@@ -0,0 +1,36 @@
+package datasource
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Datasource is a database with separate read and write connections,
+// implemented by Mysql and Sqlite. The Ctx variants return a handle whose
+// statements are aborted once ctx is done or the query timeout expires,
+// counted from the call. Statements run through gorm callbacks, Exec is not
+// bound to ctx.
+type Datasource interface {
+	Init()
+	Read() *gorm.DB
+	Write() *gorm.DB
+	ReadCtx(ctx context.Context) *gorm.DB
+	WriteCtx(ctx context.Context) *gorm.DB
+	Error() error
+	SetDebug(debug bool)
+	SetQueryLogger(q *QueryLogger)
+}
+
+func setLogger(db *gorm.DB, debug bool, q *QueryLogger) {
+	if q == nil {
+		db.LogMode(debug)
+		return
+	}
+
+	if debug {
+		q.SetDebug(true)
+	}
+	db.SetLogger(q)
+	db.LogMode(true)
+}
@@ -0,0 +1,202 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// drivers registered by this package, they wrap the mysql and sqlite3
+// drivers with contextDriver
+const (
+	mysqlDriver  = "gocore:mysql"
+	sqliteDriver = "gocore:sqlite3"
+)
+
+func init() {
+	sql.Register(mysqlDriver, &contextDriver{Driver: &mysql.MySQLDriver{}, executionTime: true})
+	sql.Register(sqliteDriver, &contextDriver{Driver: &sqlite3.SQLiteDriver{}})
+}
+
+// contextDriver runs statements tagged by the ReadCtx and WriteCtx handles
+// with the context of the handle, the driver aborts the statement once the
+// context is done and the connection goes back to the pool. With
+// executionTime, SELECT statements with a deadline carry a
+// MAX_EXECUTION_TIME hint so mysql also stops them on the server.
+type contextDriver struct {
+	driver.Driver
+	executionTime bool
+}
+
+func (d *contextDriver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &contextConn{Conn: c, executionTime: d.executionTime}, nil
+}
+
+type contextConn struct {
+	driver.Conn
+	executionTime bool
+}
+
+// bind returns the query to run and its context, ctx unless query is tagged
+func (c *contextConn) bind(ctx context.Context, query string) (context.Context, string) {
+	query, tagged := untag(query)
+	if tagged != nil {
+		ctx = tagged
+	}
+	if deadline, ok := ctx.Deadline(); ok && c.executionTime {
+		query = executionTimeHint(query, time.Until(deadline))
+	}
+	return ctx, query
+}
+
+func (c *contextConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *contextConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	bound, query := c.bind(ctx, query)
+
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(bound, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st := &contextStmt{Stmt: s}
+	if bound != ctx {
+		st.ctx = bound
+	}
+	return st, nil
+}
+
+func (c *contextConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, query = c.bind(ctx, query)
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *contextConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, query = c.bind(ctx, query)
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *contextConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *contextConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *contextConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *contextConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *contextConn) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// contextStmt runs a prepared tagged statement with the context of its
+// handle, database/sql prepares the statements the driver cannot run directly
+type contextStmt struct {
+	driver.Stmt
+	ctx context.Context
+}
+
+func (s *contextStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if s.ctx != nil {
+		ctx = s.ctx
+	}
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+func (s *contextStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if s.ctx != nil {
+		ctx = s.ctx
+	}
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("datasource: driver does not support named parameter %s", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// executionTimeHint adds a mysql MAX_EXECUTION_TIME optimizer hint to a
+// SELECT statement, other statements are returned unchanged
+func executionTimeHint(query string, timeout time.Duration) string {
+	trimmed := strings.TrimLeft(query, " \t\r\n(")
+	if len(trimmed) < 7 || !strings.EqualFold(trimmed[:6], "SELECT") || !strings.ContainsRune(" \t\r\n", rune(trimmed[6])) {
+		return query
+	}
+
+	ms := int64((timeout + time.Millisecond - 1) / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	i := len(query) - len(trimmed) + 6
+	return fmt.Sprintf("%s /*+ MAX_EXECUTION_TIME(%d) */%s", query[:i], ms, query[i:])
+}
@@ -0,0 +1,423 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/layout"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PrimaryKey is the column referenced by {{ ref }} and checked by Seed
+var PrimaryKey = "id"
+
+// TimeFormat is used to render {{ now }}
+var TimeFormat = "2006-01-02 15:04:05"
+
+var refPattern = regexp.MustCompile(`ref\s+"(([^".]+)\.[^"]+)"`)
+
+type row struct {
+	label   string
+	columns []string
+	values  []interface{}
+}
+
+type table struct {
+	name string
+	rows []*row
+	deps map[string]bool
+}
+
+// Fixtures are rows read from YAML files keyed by table then row label:
+//
+//	users:
+//	  alice:
+//	    name: Alice
+//	    created_at: "{{ now }}"
+//	orders:
+//	  first:
+//	    user_id: '{{ ref "users.alice" }}'
+//	    paid_at: '{{ nowAdd "-24h" }}'
+//
+// Tables are inserted in dependency order, so users above is inserted before
+// orders regardless of its position in the files.
+type Fixtures struct {
+	ds     datasource.Datasource
+	tables []*table
+	ids    map[string]interface{}
+}
+
+// New parses every file of loader, e.g.
+//
+//	f, err := fixture.New(mysql, layout.FSLoader("testdata/fixtures", ".yml"))
+func New(ds datasource.Datasource, loader layout.Loader) (*Fixtures, error) {
+	files, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixtures{ds: ds, ids: make(map[string]interface{})}
+	index := make(map[string]*table)
+	for _, file := range files {
+		if err := f.parse(file, index); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.sort(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Load deletes every row of the fixture tables and inserts the fixtures in a
+// single transaction, call it before each test
+func (f *Fixtures) Load() error {
+	return f.transaction(func(tx *gorm.DB) error {
+		if err := f.truncate(tx); err != nil {
+			return err
+		}
+		return f.insert(tx, false)
+	})
+}
+
+// Seed inserts fixtures without deleting existing data, rows whose primary
+// key already exists are skipped, rows without one are only inserted into
+// empty tables and rows referencing a skipped row are skipped too. It is safe
+// to run on every start of a dev environment.
+func (f *Fixtures) Seed() error {
+	return f.transaction(func(tx *gorm.DB) error {
+		return f.insert(tx, true)
+	})
+}
+
+// Truncate deletes every row of the fixture tables, DELETE is used instead
+// of TRUNCATE so it can run in a transaction
+func (f *Fixtures) Truncate() error {
+	return f.transaction(f.truncate)
+}
+
+// ID returns the primary key of a loaded row, ref is "table.label"
+func (f *Fixtures) ID(ref string) interface{} {
+	return f.ids[ref]
+}
+
+func (f *Fixtures) transaction(fn func(tx *gorm.DB) error) error {
+	tx := f.ds.Write().Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (f *Fixtures) truncate(tx *gorm.DB) error {
+	scope := tx.NewScope(nil)
+	for i := len(f.tables) - 1; i >= 0; i-- {
+		if err := tx.Exec("DELETE FROM " + scope.Quote(f.tables[i].name)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fixtures) insert(tx *gorm.DB, seed bool) error {
+	scope := tx.NewScope(nil)
+	db := tx.CommonDB()
+
+	for _, t := range f.tables {
+		empty := true
+		if seed {
+			var n int
+			if err := db.QueryRow("SELECT COUNT(*) FROM " + scope.Quote(t.name)).Scan(&n); err != nil {
+				return err
+			}
+			empty = n == 0
+		}
+
+		for _, r := range t.rows {
+			ref := t.name + "." + r.label
+			if seed && f.skipped(r) {
+				f.ids[ref] = nil
+				continue
+			}
+
+			values, err := f.render(r.values)
+			if err != nil {
+				return fmt.Errorf("fixture %s: %v", ref, err)
+			}
+
+			id := primaryKey(r.columns, values)
+			if seed {
+				if id == nil && !empty {
+					f.ids[ref] = nil
+					continue
+				}
+				if id != nil {
+					var n int
+					q := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", scope.Quote(t.name), scope.Quote(PrimaryKey))
+					if err := db.QueryRow(q, id).Scan(&n); err != nil {
+						return err
+					}
+					if n > 0 {
+						f.ids[ref] = id
+						continue
+					}
+				}
+			}
+
+			columns := make([]string, len(r.columns))
+			for i, c := range r.columns {
+				columns[i] = scope.Quote(c)
+			}
+			q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+				scope.Quote(t.name),
+				strings.Join(columns, ", "),
+				strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+			res, err := db.Exec(q, values...)
+			if err != nil {
+				return fmt.Errorf("fixture %s: %v", ref, err)
+			}
+			if id == nil {
+				if last, err := res.LastInsertId(); err == nil {
+					id = last
+				}
+			}
+			f.ids[ref] = id
+		}
+	}
+
+	return nil
+}
+
+// skipped reports whether r references a row skipped by Seed
+func (f *Fixtures) skipped(r *row) bool {
+	for _, v := range r.values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		for _, m := range refPattern.FindAllStringSubmatch(s, -1) {
+			if id, ok := f.ids[m[1]]; ok && id == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render executes templated string values
+func (f *Fixtures) render(values []interface{}) ([]interface{}, error) {
+	funcs := template.FuncMap{
+		"now": func() string {
+			return time.Now().Format(TimeFormat)
+		},
+		"nowAdd": func(d string) (string, error) {
+			offset, err := time.ParseDuration(d)
+			if err != nil {
+				return "", err
+			}
+			return time.Now().Add(offset).Format(TimeFormat), nil
+		},
+		"ref": func(ref string) (interface{}, error) {
+			id, ok := f.ids[ref]
+			if !ok || id == nil {
+				return nil, fmt.Errorf("unknown reference %s", ref)
+			}
+			return id, nil
+		},
+	}
+
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok || !strings.Contains(s, "{{") {
+			out[i] = v
+			continue
+		}
+
+		tpl, err := template.New("value").Funcs(funcs).Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, nil); err != nil {
+			return nil, err
+		}
+		out[i] = buf.String()
+	}
+
+	return out, nil
+}
+
+func (f *Fixtures) parse(file layout.File, index map[string]*table) error {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(file.Content, &doc); err != nil {
+		return fmt.Errorf("fixture %s: %v", file.Name, err)
+	}
+
+	for _, item := range doc {
+		name := fmt.Sprint(item.Key)
+		t, ok := index[name]
+		if !ok {
+			t = &table{name: name, deps: make(map[string]bool)}
+			index[name] = t
+			f.tables = append(f.tables, t)
+		}
+
+		var rows []yaml.MapItem
+		switch v := item.Value.(type) {
+		case yaml.MapSlice:
+			rows = v
+		case []interface{}:
+			for i, r := range v {
+				rows = append(rows, yaml.MapItem{Key: strconv.Itoa(len(t.rows) + i), Value: r})
+			}
+		case nil:
+		default:
+			return fmt.Errorf("fixture %s: %s must be a map or a list of rows", file.Name, name)
+		}
+
+		for _, r := range rows {
+			values, ok := r.Value.(yaml.MapSlice)
+			if !ok {
+				return fmt.Errorf("fixture %s: %s.%v must be a map of columns", file.Name, name, r.Key)
+			}
+
+			fr := &row{label: fmt.Sprint(r.Key)}
+			for _, c := range values {
+				v, err := value(c.Value)
+				if err != nil {
+					return fmt.Errorf("fixture %s: %s.%v.%v %v", file.Name, name, r.Key, c.Key, err)
+				}
+				if s, ok := v.(string); ok {
+					for _, m := range refPattern.FindAllStringSubmatch(s, -1) {
+						if m[2] != name {
+							t.deps[m[2]] = true
+						}
+					}
+				}
+				fr.columns = append(fr.columns, fmt.Sprint(c.Key))
+				fr.values = append(fr.values, v)
+			}
+			t.rows = append(t.rows, fr)
+		}
+	}
+
+	return nil
+}
+
+// sort orders tables so referenced tables come first, keeping file order
+// otherwise
+func (f *Fixtures) sort() error {
+	var (
+		sorted = make([]*table, 0, len(f.tables))
+		state  = make(map[string]int)
+		index  = make(map[string]*table, len(f.tables))
+		visit  func(t *table, path []string) error
+	)
+	for _, t := range f.tables {
+		index[t.name] = t
+	}
+
+	visit = func(t *table, path []string) error {
+		switch state[t.name] {
+		case 1:
+			return fmt.Errorf("fixture: circular reference %s", strings.Join(append(path, t.name), " -> "))
+		case 2:
+			return nil
+		}
+
+		state[t.name] = 1
+		for _, dep := range f.tables {
+			if !t.deps[dep.name] {
+				continue
+			}
+			if err := visit(dep, append(path, t.name)); err != nil {
+				return err
+			}
+		}
+		for dep := range t.deps {
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("fixture: %s references unknown table %s", t.name, dep)
+			}
+		}
+		state[t.name] = 2
+		sorted = append(sorted, t)
+
+		return nil
+	}
+
+	for _, t := range f.tables {
+		if err := visit(t, nil); err != nil {
+			return err
+		}
+	}
+	f.tables = sorted
+
+	return nil
+}
+
+func primaryKey(columns []string, values []interface{}) interface{} {
+	for i, c := range columns {
+		if c == PrimaryKey {
+			return values[i]
+		}
+	}
+	return nil
+}
+
+// value converts yaml values to sql arguments, maps and lists are stored as
+// json
+func value(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, string, int, int64, uint64, float64, bool, time.Time:
+		return val, nil
+	case yaml.MapSlice, []interface{}, map[interface{}]interface{}:
+		b, err := json.Marshal(jsonValue(val))
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(val))
+		for _, item := range val {
+			m[fmt.Sprint(item.Key)] = jsonValue(item.Value)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = jsonValue(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
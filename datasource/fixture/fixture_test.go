@@ -0,0 +1,141 @@
+package fixture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/layout"
+)
+
+type memLoader map[string]string
+
+func (l memLoader) Load() ([]layout.File, error) {
+	var files []layout.File
+	for name, content := range l {
+		files = append(files, layout.File{Name: name, Content: []byte(content)})
+	}
+	return files, nil
+}
+
+type user struct {
+	ID   uint
+	Name string
+}
+
+type order struct {
+	ID     uint
+	UserID uint
+	Total  int
+	Meta   string
+	PaidAt string
+}
+
+// orders comes first in the file to check dependency ordering
+const fixtures = `
+orders:
+  first:
+    user_id: '{{ ref "users.alice" }}'
+    total: 10
+    meta: {source: web, tags: [a, b]}
+    paid_at: '{{ nowAdd "-24h" }}'
+  second:
+    user_id: '{{ ref "users.bob" }}'
+    total: 20
+users:
+  alice:
+    id: 7
+    name: Alice
+  bob:
+    name: Bob
+`
+
+func setup(t *testing.T) (*datasource.Sqlite, *Fixtures) {
+	ds := datasource.NewSqlite(":memory:")
+	ds.Write().AutoMigrate(&user{}, &order{})
+
+	f, err := New(ds, memLoader{"data.yml": fixtures})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds, f
+}
+
+func TestLoad(t *testing.T) {
+	ds, f := setup(t)
+	defer ds.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := f.Load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var users []user
+	ds.Read().Order("name").Find(&users)
+	if len(users) != 2 || users[0].ID != 7 {
+		t.Fatalf("loading twice should replace rows, got %+v", users)
+	}
+
+	var orders []order
+	ds.Read().Order("total").Find(&orders)
+	if len(orders) != 2 {
+		t.Fatalf("should load 2 orders, got %d", len(orders))
+	}
+	if orders[0].UserID != 7 || f.ID("users.bob") == nil || orders[1].UserID != users[1].ID {
+		t.Errorf("references should resolve to primary keys, got %+v", orders)
+	}
+	if orders[0].Meta != `{"source":"web","tags":["a","b"]}` {
+		t.Errorf("maps should be stored as json, got %s", orders[0].Meta)
+	}
+	if orders[0].PaidAt == "" || strings.Contains(orders[0].PaidAt, "{{") {
+		t.Errorf("time template should be rendered, got %q", orders[0].PaidAt)
+	}
+
+	if err := f.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	ds.Read().Model(&user{}).Count(&count)
+	if count != 0 {
+		t.Errorf("truncate should delete rows, got %d", count)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	ds, f := setup(t)
+	defer ds.Close()
+
+	ds.Write().Create(&user{ID: 7, Name: "Existing"})
+
+	for i := 0; i < 2; i++ {
+		if err := f.Seed(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var users []user
+	ds.Read().Find(&users)
+	if len(users) != 1 || users[0].Name != "Existing" {
+		t.Errorf("seed should keep existing rows, got %+v", users)
+	}
+
+	var count int
+	ds.Read().Model(&order{}).Count(&count)
+	if count != 1 {
+		t.Errorf("seed should skip orders of skipped users, got %d orders", count)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	cases := map[string]string{
+		"a.yml": "a:\n  x:\n    b_id: '{{ ref \"b.y\" }}'\nb:\n  y:\n    a_id: '{{ ref \"a.x\" }}'\n",
+		"b.yml": "a:\n  x:\n    b_id: '{{ ref \"missing.y\" }}'\n",
+		"c.yml": "a: 1\n",
+	}
+	for name, content := range cases {
+		if _, err := New(nil, memLoader{name: content}); err == nil {
+			t.Errorf("%s should fail", name)
+		}
+	}
+}
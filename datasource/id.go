@@ -0,0 +1,93 @@
+package datasource
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ids = &idSource{}
+
+// idSource produces 48 bit millisecond timestamps followed by 80 random
+// bits, the random part is incremented within the same millisecond so ids
+// made by one process are strictly ordered
+type idSource struct {
+	mu      sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+func (s *idSource) next() [16]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	if ms > s.ms {
+		s.ms = ms
+		if _, err := rand.Read(s.entropy[:]); err != nil {
+			panic(err)
+		}
+	} else {
+		for i := len(s.entropy) - 1; i >= 0; i-- {
+			s.entropy[i]++
+			if s.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:2], uint16(s.ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(s.ms))
+	copy(id[6:], s.entropy[:])
+
+	return id
+}
+
+// NewULID returns a 26 character, lexically sortable ULID
+func NewULID() string {
+	id := ids.next()
+
+	// 128 bits as 26 base32 characters, the first one carries 3 bits
+	out := make([]byte, 26)
+	var acc uint16
+	var bits uint
+	pos := len(out) - 1
+	for i := len(id) - 1; i >= 0; i-- {
+		acc |= uint16(id[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[pos] = crockford[acc&31]
+			acc >>= 5
+			bits -= 5
+			pos--
+		}
+	}
+	out[pos] = crockford[acc&31]
+
+	return string(out)
+}
+
+// NewUUID returns a time ordered version 7 UUID
+func NewUUID() string {
+	id := ids.next()
+	id[6] = id[6]&0x0f | 0x70
+	id[8] = id[8]&0x3f | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	return string(buf)
+}
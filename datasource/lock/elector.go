@@ -0,0 +1,142 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/maps90/go-core/log"
+)
+
+// Elector picks one leader among the instances competing for the same lock
+// name, e.g. to run scheduled jobs once per cluster:
+//
+//	e := lock.NewElector(mysql.Write().DB(), "billing:cron")
+//	e.OnElected(func(ctx context.Context) { scheduler.Run(ctx) })
+//	go e.Run(context.Background())
+//	router.Setup().OnShutdown(e.Stop)
+type Elector struct {
+	lock      *Lock
+	retry     time.Duration
+	onElected func(ctx context.Context)
+	onRevoked func()
+
+	mu     sync.Mutex
+	leader bool
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewElector(db *sql.DB, name string) *Elector {
+	return &Elector{
+		lock:  New(db, name),
+		retry: 5 * time.Second,
+	}
+}
+
+// SetTTL sets the lease of the underlying lock, see Lock.SetTTL
+func (e *Elector) SetTTL(ttl time.Duration) {
+	e.lock.SetTTL(ttl)
+}
+
+// SetRetryInterval sets how often followers try to become leader,
+// defaults to 5s
+func (e *Elector) SetRetryInterval(d time.Duration) {
+	e.retry = d
+}
+
+// OnElected is called in its own goroutine when this instance becomes
+// leader, ctx is cancelled when leadership is lost
+func (e *Elector) OnElected(fn func(ctx context.Context)) {
+	e.onElected = fn
+}
+
+// OnRevoked is called after leadership is lost or given up
+func (e *Elector) OnRevoked(fn func()) {
+	e.onRevoked = fn
+}
+
+// IsLeader reports whether this instance currently leads
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.leader
+}
+
+// Run campaigns for leadership until ctx is done or Stop is called,
+// leadership is given up before it returns
+func (e *Elector) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.mu.Lock()
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	done := e.done
+	e.mu.Unlock()
+
+	defer close(done)
+	defer cancel()
+
+	for {
+		ok, err := e.lock.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.NewWithFields(log.ErrorLevelLog, "lock", log.Fields{"lock": e.lock.Name()}, "campaign: ", err.Error())
+		}
+		if ok {
+			e.lead(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// Stop gives up leadership and waits for Run to return
+func (e *Elector) Stop() {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// lead runs the leader callbacks until the lease is lost or ctx is done
+func (e *Elector) lead(ctx context.Context) {
+	e.setLeader(true)
+	log.NewWithFields(log.InfoLevelLog, "lock", log.Fields{"lock": e.lock.Name()}, "elected")
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	if e.onElected != nil {
+		go e.onElected(leaderCtx)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-e.lock.Lost():
+	}
+	cancel()
+
+	if err := e.lock.Release(); err != nil {
+		log.NewWithFields(log.ErrorLevelLog, "lock", log.Fields{"lock": e.lock.Name()}, "release: ", err.Error())
+	}
+	e.setLeader(false)
+	log.NewWithFields(log.InfoLevelLog, "lock", log.Fields{"lock": e.lock.Name()}, "revoked")
+
+	if e.onRevoked != nil {
+		e.onRevoked()
+	}
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+}
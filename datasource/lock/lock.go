@@ -0,0 +1,236 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/maps90/go-core/log"
+)
+
+const (
+	// DefaultTTL is the lease of a new Lock
+	DefaultTTL = 30 * time.Second
+	// MinTTL is the shortest lease accepted by SetTTL
+	MinTTL = 30 * time.Millisecond
+)
+
+var (
+	// ErrNotAcquired is returned when the lock is held by another session
+	ErrNotAcquired = errors.New("lock: not acquired")
+	// ErrNotHeld is returned when releasing a lock that is not held
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+// Lock is a named mysql lock taken with GET_LOCK. MySQL keeps the lock until
+// the session ends, so each Lock holds a dedicated connection and checks it
+// every ttl / 3. When the lock or the connection is lost, or the checks time
+// out for longer than ttl, the connection is closed and Lost is signalled.
+type Lock struct {
+	db   *sql.DB
+	name string
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	conn   *sql.Conn
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns an unlocked Lock, names are global to the mysql server
+func New(db *sql.DB, name string) *Lock {
+	return &Lock{
+		db:   db,
+		name: name,
+		ttl:  DefaultTTL,
+	}
+}
+
+// SetTTL sets the lease, defaults to DefaultTTL. A ttl <= 0 restores the
+// default, a shorter ttl than MinTTL is raised to MinTTL.
+func (l *Lock) SetTTL(ttl time.Duration) {
+	switch {
+	case ttl <= 0:
+		ttl = DefaultTTL
+	case ttl < MinTTL:
+		ttl = MinTTL
+	}
+	l.ttl = ttl
+}
+
+// Name returns the lock name
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Acquire waits up to timeout for the lock, it returns ErrNotAcquired when
+// the timeout expires. GET_LOCK counts in seconds, a timeout is rounded up
+// to the next second.
+func (l *Lock) Acquire(ctx context.Context, timeout time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		select {
+		case <-l.lost:
+			l.cancel()
+			l.conn.Close()
+			l.conn = nil
+		default:
+			return nil
+		}
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ok sql.NullInt64
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.name, seconds).Scan(&ok)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !ok.Valid || ok.Int64 != 1 {
+		conn.Close()
+		return ErrNotAcquired
+	}
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	l.conn = conn
+	l.lost = make(chan struct{})
+	l.cancel = cancel
+	l.done = make(chan struct{})
+	go l.renew(renewCtx, conn, l.lost, l.done)
+
+	return nil
+}
+
+// TryAcquire takes the lock without waiting
+func (l *Lock) TryAcquire(ctx context.Context) (bool, error) {
+	err := l.Acquire(ctx, 0)
+	if err == ErrNotAcquired {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release frees the lock and its connection
+func (l *Lock) Release() error {
+	l.mu.Lock()
+	conn, cancel, done, lost := l.conn, l.cancel, l.done, l.lost
+	l.conn = nil
+	l.mu.Unlock()
+
+	if conn == nil {
+		return ErrNotHeld
+	}
+
+	cancel()
+	<-done
+	defer conn.Close()
+
+	select {
+	case <-lost:
+		// the session was discarded with the lock
+		return nil
+	default:
+	}
+
+	ctx, stop := context.WithTimeout(context.Background(), l.ttl)
+	defer stop()
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", l.name)
+
+	return err
+}
+
+// Held reports whether the lock is held and its lease is valid
+func (l *Lock) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return false
+	}
+	select {
+	case <-l.lost:
+		return false
+	default:
+		return true
+	}
+}
+
+// Lost is closed when the lease could not be renewed, it is nil before the
+// lock is acquired
+func (l *Lock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lost
+}
+
+func (l *Lock) renew(ctx context.Context, conn *sql.Conn, lost, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := l.check(ctx, conn)
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.NewWithFields(log.WarnLevelLog, "lock", log.Fields{"lock": l.name}, "renew: ", err.Error())
+		// mysql frees the lock as soon as the session dies, only a slow
+		// session keeps it
+		if !timeout(err) || time.Since(renewed) >= l.ttl {
+			log.NewWithFields(log.ErrorLevelLog, "lock", log.Fields{"lock": l.name}, "lease lost")
+			// discard the session instead of returning it to the pool, mysql
+			// frees the lock if we still hold it
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			close(lost)
+			return
+		}
+	}
+}
+
+// timeout reports whether err is a check timing out on a live session
+func timeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ne) && ne.Timeout()
+}
+
+// check confirms that the session is alive and still owns the lock
+func (l *Lock) check(ctx context.Context, conn *sql.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, l.ttl/3)
+	defer cancel()
+
+	var owned sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", l.name).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if !owned.Valid || owned.Int64 != 1 {
+		return ErrNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maps90/go-core/log"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// server emulates the mysql named lock functions on sqlite connections
+type server struct {
+	mu    sync.Mutex
+	locks map[string]int64
+	dead  map[int64]bool
+	conns int64
+}
+
+var fake = &server{locks: make(map[string]int64), dead: make(map[int64]bool)}
+
+func init() {
+	sql.Register("sqlite3_lock", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			id := atomic.AddInt64(&fake.conns, 1)
+			funcs := map[string]interface{}{
+				"CONNECTION_ID": func() int64 { return id },
+				"GET_LOCK":      func(name string, timeout int64) int64 { return fake.get(id, name, timeout) },
+				"RELEASE_LOCK":  func(name string) int64 { return fake.release(id, name) },
+				"IS_USED_LOCK":  func(name string) (int64, error) { return fake.owner(id, name) },
+			}
+			for name, fn := range funcs {
+				if err := conn.RegisterFunc(name, fn, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	log.Init("", false)
+}
+
+func (s *server) get(id int64, name string, timeout int64) int64 {
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for {
+		s.mu.Lock()
+		if owner, ok := s.locks[name]; !ok || owner == id {
+			s.locks[name] = id
+			s.mu.Unlock()
+			return 1
+		}
+		s.mu.Unlock()
+
+		if time.Now().After(deadline) {
+			return 0
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *server) release(id int64, name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.locks[name] != id {
+		return 0
+	}
+	delete(s.locks, name)
+	return 1
+}
+
+func (s *server) owner(id int64, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dead[id] {
+		return 0, errors.New("invalid connection")
+	}
+	return s.locks[name], nil
+}
+
+func (s *server) kill(name string) {
+	s.mu.Lock()
+	delete(s.locks, name)
+	s.mu.Unlock()
+}
+
+// killConn ends the session holding name, which frees the lock
+func (s *server) killConn(name string) {
+	s.mu.Lock()
+	s.dead[s.locks[name]] = true
+	delete(s.locks, name)
+	s.mu.Unlock()
+}
+
+func open(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3_lock", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLock(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	a, b := New(db, "test:lock"), New(db, "test:lock")
+	if ok, err := a.TryAcquire(ctx); !ok || err != nil {
+		t.Fatalf("first lock should be acquired, got %v %v", ok, err)
+	}
+	if ok, err := b.TryAcquire(ctx); ok || err != nil {
+		t.Fatalf("second lock should not be acquired, got %v %v", ok, err)
+	}
+	if !a.Held() || b.Held() {
+		t.Error("only the first lock should be held")
+	}
+
+	if err := a.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Release(); err != ErrNotHeld {
+		t.Errorf("releasing twice should return ErrNotHeld, got %v", err)
+	}
+	if ok, _ := b.TryAcquire(ctx); !ok {
+		t.Error("released lock should be acquired")
+	}
+	b.Release()
+}
+
+func TestLockTimeout(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	a, b := New(db, "test:timeout"), New(db, "test:timeout")
+	if err := a.Acquire(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(200*time.Millisecond, func() { a.Release() })
+
+	// a sub-second timeout waits instead of failing at once
+	if err := b.Acquire(ctx, 500*time.Millisecond); err != nil {
+		t.Errorf("lock should be acquired once released, got %v", err)
+	}
+	b.Release()
+}
+
+func TestLockSetTTL(t *testing.T) {
+	l := New(nil, "test:ttl")
+	for ttl, want := range map[time.Duration]time.Duration{
+		0:                DefaultTTL,
+		-time.Second:     DefaultTTL,
+		time.Nanosecond:  MinTTL,
+		time.Millisecond: MinTTL,
+		time.Second:      time.Second,
+	} {
+		l.SetTTL(ttl)
+		if l.ttl != want {
+			t.Errorf("SetTTL(%v) should set %v, got %v", ttl, want, l.ttl)
+		}
+	}
+}
+
+func TestLockLost(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+
+	l := New(db, "test:lost")
+	l.SetTTL(90 * time.Millisecond)
+	if err := l.Acquire(context.Background(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.kill("test:lost")
+
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease should be lost")
+	}
+	if l.Held() {
+		t.Error("lost lock should not be held")
+	}
+	if err := l.Release(); err != nil {
+		t.Errorf("releasing a lost lock should not fail, got %v", err)
+	}
+}
+
+func TestLockConnectionLost(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+
+	l := New(db, "test:conn")
+	l.SetTTL(900 * time.Millisecond)
+	if err := l.Acquire(context.Background(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	fake.killConn("test:conn")
+
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease should be lost")
+	}
+	// the first check after the kill, ttl / 3, gives up the lock
+	if elapsed := time.Since(start); elapsed > 600*time.Millisecond {
+		t.Errorf("dead connection should lose the lock at once, took %v", elapsed)
+	}
+	l.Release()
+}
+
+func TestElector(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+
+	elected := make(chan string, 2)
+	revoked := make(chan string, 2)
+	elector := func(name string) *Elector {
+		e := NewElector(db, "test:leader")
+		e.SetTTL(90 * time.Millisecond)
+		e.SetRetryInterval(20 * time.Millisecond)
+		e.OnElected(func(ctx context.Context) {
+			elected <- name
+			<-ctx.Done()
+		})
+		e.OnRevoked(func() { revoked <- name })
+		return e
+	}
+
+	a, b := elector("a"), elector("b")
+	go a.Run(context.Background())
+	if got := <-elected; got != "a" {
+		t.Fatalf("a should be elected, got %s", got)
+	}
+
+	go b.Run(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatal("only a should lead")
+	}
+
+	a.Stop()
+	if got := <-revoked; got != "a" {
+		t.Errorf("a should be revoked, got %s", got)
+	}
+
+	select {
+	case got := <-elected:
+		if got != "b" {
+			t.Errorf("b should be elected, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("b should take over")
+	}
+	b.Stop()
+}
@@ -0,0 +1,178 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maps90/go-core/layout"
+)
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+var filePattern = regexp.MustCompile(`^(\d+)_([\w\-]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum returns sha256 of the up script, used to detect edited migrations
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasDown reports whether the migration can be rolled back
+func (m *Migration) HasDown() bool {
+	return len(strings.TrimSpace(m.Down)) > 0
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql files from loader and
+// returns migrations sorted by version
+func Load(loader layout.Loader) ([]*Migration, error) {
+	files, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, file := range files {
+		base := filepath.Base(file.Name)
+		if !strings.HasSuffix(base, upSuffix) && !strings.HasSuffix(base, downSuffix) {
+			continue
+		}
+
+		match := filePattern.FindStringSubmatch(base)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", file.Name)
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", file.Name)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(file.Content)
+		} else {
+			m.Down = string(file.Content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(strings.TrimSpace(m.Up)) == 0 {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func sortStatus(list []Status) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+}
+
+func sortRecords(list []record) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].version < list[j].version
+	})
+}
+
+// parseTime handles DATETIME returned with and without parseTime=true
+func parseTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// splitStatements splits a sql script on semicolons, ignoring the ones
+// inside quotes and comments
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      rune
+	)
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case quote != 0:
+			current.WriteRune(r)
+			if r == '\\' && quote != '`' && next != 0 {
+				current.WriteRune(next)
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+			current.WriteRune(r)
+		case r == '-' && next == '-', r == '#':
+			// skip line comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && next == '*':
+			// keep block comments, mysql uses /*! ... */ for conditional code
+			start := i
+			for ; i < len(runes); i++ {
+				current.WriteRune(runes[i])
+				if i > start+2 && runes[i] == '/' && runes[i-1] == '*' {
+					break
+				}
+			}
+		case r == ';':
+			if s := strings.TrimSpace(current.String()); len(s) > 0 {
+				statements = append(statements, s)
+			}
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if s := strings.TrimSpace(current.String()); len(s) > 0 {
+		statements = append(statements, s)
+	}
+
+	return statements
+}
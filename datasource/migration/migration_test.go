@@ -0,0 +1,79 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/maps90/go-core/layout"
+)
+
+type memLoader []layout.File
+
+func (l memLoader) Load() ([]layout.File, error) {
+	return l, nil
+}
+
+func TestLoad(t *testing.T) {
+	loader := memLoader{
+		{Name: "0002_add_email.up.sql", Content: []byte("ALTER TABLE users ADD email VARCHAR(255);")},
+		{Name: "0002_add_email.down.sql", Content: []byte("ALTER TABLE users DROP email;")},
+		{Name: "sub/0001_create_users.up.sql", Content: []byte("CREATE TABLE users (id INT);")},
+		{Name: "README.md", Content: []byte("ignored")},
+	}
+
+	migrations, err := Load(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("should load 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_users" {
+		t.Error("migrations should be sorted by version")
+	}
+	if migrations[0].HasDown() {
+		t.Error("0001 should not have down script")
+	}
+	if !migrations[1].HasDown() {
+		t.Error("0002 should have down script")
+	}
+	if migrations[0].Checksum() == migrations[1].Checksum() {
+		t.Error("checksum should differ for different scripts")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(memLoader{{Name: "create_users.up.sql"}}); err == nil {
+		t.Error("file without version should be invalid")
+	}
+	if _, err := Load(memLoader{{Name: "0001_users.down.sql", Content: []byte("DROP TABLE users")}}); err == nil {
+		t.Error("migration without up script should be invalid")
+	}
+	if _, err := Load(memLoader{
+		{Name: "0001_a.up.sql", Content: []byte("SELECT 1")},
+		{Name: "0001_b.up.sql", Content: []byte("SELECT 1")},
+	}); err == nil {
+		t.Error("duplicate version should be invalid")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- create table; with comment
+CREATE TABLE users (id INT, name VARCHAR(10) DEFAULT 'a;b');
+/*!40101 SET NAMES utf8; */;
+INSERT INTO users VALUES (1, "it\"s;");
+# trailing comment;
+`
+	statements := splitStatements(script)
+	if len(statements) != 3 {
+		t.Fatalf("should split into 3 statements, got %d: %q", len(statements), statements)
+	}
+	if statements[0] != "CREATE TABLE users (id INT, name VARCHAR(10) DEFAULT 'a;b')" {
+		t.Errorf("unexpected statement %q", statements[0])
+	}
+	if statements[1] != "/*!40101 SET NAMES utf8; */" {
+		t.Errorf("unexpected statement %q", statements[1])
+	}
+	if statements[2] != `INSERT INTO users VALUES (1, "it\"s;")` {
+		t.Errorf("unexpected statement %q", statements[2])
+	}
+}
@@ -0,0 +1,406 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/maps90/go-core/layout"
+	"github.com/maps90/go-core/log"
+)
+
+var (
+	// ErrLocked is returned when another instance holds the migration lock
+	ErrLocked = errors.New("migration: another instance is migrating")
+	// ErrChecksum is wrapped by the error returned when an applied migration
+	// file has been edited, check it with errors.Is
+	ErrChecksum = errors.New("migration: checksum mismatch")
+)
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Version   uint64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Dirty     bool
+	Missing   bool
+}
+
+type record struct {
+	version   uint64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies versioned migrations to a mysql database
+type Migrator struct {
+	db          *sql.DB
+	loader      layout.Loader
+	table       string
+	lockName    string
+	lockTimeout time.Duration
+	dryRun      bool
+	out         io.Writer
+}
+
+// New returns Migrator that reads migrations from loader, e.g.
+//
+//	m := migration.New(mysql.Write().DB(), layout.FSLoader("migrations", ".sql"))
+func New(db *sql.DB, loader layout.Loader) *Migrator {
+	return &Migrator{
+		db:          db,
+		loader:      loader,
+		table:       "schema_migrations",
+		lockName:    "go-core:migration",
+		lockTimeout: 10 * time.Second,
+		out:         os.Stdout,
+	}
+}
+
+// SetTable sets the table used to record applied versions
+func (m *Migrator) SetTable(name string) {
+	m.table = name
+}
+
+// SetLockName sets the name passed to GET_LOCK
+func (m *Migrator) SetLockName(name string) {
+	m.lockName = name
+}
+
+// SetLockTimeout sets how long to wait for another instance to finish
+func (m *Migrator) SetLockTimeout(t time.Duration) {
+	m.lockTimeout = t
+}
+
+// SetDryRun prints the statements to output instead of executing them
+func (m *Migrator) SetDryRun(dry bool) {
+	m.dryRun = dry
+}
+
+// SetOutput sets the dry-run writer, defaults to stdout
+func (m *Migrator) SetOutput(w io.Writer) {
+	m.out = w
+}
+
+// Up applies all pending migrations, it never rolls back. Applied versions
+// without a local file, e.g. applied by a newer release, are kept with a
+// warning.
+func (m *Migrator) Up() error {
+	return m.run(func(s *session) error {
+		for _, r := range s.records() {
+			if s.find(r.version) == nil {
+				log.New(log.WarnLevelLog, "migration", "no file for applied version ", fmt.Sprint(r.version))
+			}
+		}
+
+		for _, mig := range s.migrations {
+			if _, ok := s.applied[mig.Version]; ok {
+				continue
+			}
+			if err := s.up(mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last applied migration
+func (m *Migrator) Down() error {
+	return m.run(func(s *session) error {
+		current := s.current()
+		if current == 0 {
+			return nil
+		}
+		return s.down(current)
+	})
+}
+
+// To migrates up or down until version is the latest applied migration,
+// version 0 rolls back everything
+func (m *Migrator) To(version uint64) error {
+	return m.run(func(s *session) error {
+		if version != 0 && s.find(version) == nil {
+			return fmt.Errorf("migration: version %d not found", version)
+		}
+		return s.migrateTo(version)
+	})
+}
+
+// Status returns every known migration, local or applied, in version order.
+// The migrations table is created when missing, nothing is applied yet.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := Load(m.loader)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	s := &session{Migrator: m, ctx: ctx, conn: conn, migrations: migrations}
+	if err := s.ensureTable(); err != nil {
+		return nil, err
+	}
+	if err := s.loadApplied(); err != nil {
+		return nil, err
+	}
+
+	var list []Status
+	for _, mig := range migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := s.applied[mig.Version]; ok {
+			at := r.appliedAt
+			st.Applied = true
+			st.AppliedAt = &at
+			st.Dirty = r.checksum != mig.Checksum()
+		}
+		list = append(list, st)
+	}
+	for _, r := range s.records() {
+		if s.find(r.version) == nil {
+			at := r.appliedAt
+			list = append(list, Status{Version: r.version, Name: r.name, Applied: true, AppliedAt: &at, Missing: true})
+		}
+	}
+	sortStatus(list)
+
+	return list, nil
+}
+
+func (m *Migrator) run(fn func(s *session) error) error {
+	migrations, err := Load(m.loader)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	// GET_LOCK is bound to the session, so every statement runs on one connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s := &session{Migrator: m, ctx: ctx, conn: conn, migrations: migrations}
+	if !m.dryRun {
+		if err := s.lock(); err != nil {
+			return err
+		}
+		defer s.unlock()
+	}
+
+	if err := s.ensureTable(); err != nil {
+		return err
+	}
+	if err := s.loadApplied(); err != nil {
+		return err
+	}
+	if err := s.verify(); err != nil {
+		return err
+	}
+
+	return fn(s)
+}
+
+type session struct {
+	*Migrator
+	ctx        context.Context
+	conn       *sql.Conn
+	migrations []*Migration
+	applied    map[uint64]record
+}
+
+func (s *session) lock() error {
+	var ok sql.NullInt64
+	// GET_LOCK waits whole seconds, round up so a short timeout still waits
+	timeout := int((s.lockTimeout + time.Second - 1) / time.Second)
+	if err := s.conn.QueryRowContext(s.ctx, "SELECT GET_LOCK(?, ?)", s.lockName, timeout).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok.Valid || ok.Int64 != 1 {
+		return ErrLocked
+	}
+	return nil
+}
+
+func (s *session) unlock() {
+	if _, err := s.conn.ExecContext(s.ctx, "SELECT RELEASE_LOCK(?)", s.lockName); err != nil {
+		log.New(log.ErrorLevelLog, "migration", "release lock: ", err.Error())
+	}
+}
+
+func (s *session) ensureTable() error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` ("+
+		"version BIGINT UNSIGNED NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(255) NOT NULL, "+
+		"checksum CHAR(64) NOT NULL, "+
+		"applied_at DATETIME NOT NULL)", s.table)
+	if s.dryRun {
+		return nil
+	}
+	_, err := s.conn.ExecContext(s.ctx, query)
+	return err
+}
+
+func (s *session) loadApplied() error {
+	s.applied = make(map[uint64]record)
+	rows, err := s.conn.QueryContext(s.ctx, fmt.Sprintf("SELECT version, name, checksum, applied_at FROM `%s`", s.table))
+	if err != nil {
+		if s.dryRun {
+			// table is not created on dry run, nothing has been applied yet
+			return nil
+		}
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			r         record
+			appliedAt []byte
+		)
+		if err := rows.Scan(&r.version, &r.name, &r.checksum, &appliedAt); err != nil {
+			return err
+		}
+		r.appliedAt = parseTime(string(appliedAt))
+		s.applied[r.version] = r
+	}
+
+	return rows.Err()
+}
+
+func (s *session) verify() error {
+	for _, mig := range s.migrations {
+		if r, ok := s.applied[mig.Version]; ok && r.checksum != mig.Checksum() {
+			return fmt.Errorf("%w: %d_%s", ErrChecksum, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (s *session) find(version uint64) *Migration {
+	for _, mig := range s.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return nil
+}
+
+func (s *session) records() []record {
+	list := make([]record, 0, len(s.applied))
+	for _, r := range s.applied {
+		list = append(list, r)
+	}
+	sortRecords(list)
+	return list
+}
+
+func (s *session) current() uint64 {
+	list := s.records()
+	if len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1].version
+}
+
+func (s *session) migrateTo(version uint64) error {
+	// roll back everything applied above the target, newest first
+	list := s.records()
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].version <= version {
+			break
+		}
+		if err := s.down(list[i].version); err != nil {
+			return err
+		}
+	}
+
+	for _, mig := range s.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, ok := s.applied[mig.Version]; ok {
+			continue
+		}
+		if err := s.up(mig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *session) up(mig *Migration) error {
+	if err := s.exec(fmt.Sprintf("up %d_%s", mig.Version, mig.Name), mig.Up); err != nil {
+		return fmt.Errorf("migration %d_%s: %v", mig.Version, mig.Name, err)
+	}
+
+	now := time.Now().UTC()
+	if !s.dryRun {
+		_, err := s.conn.ExecContext(s.ctx,
+			fmt.Sprintf("INSERT INTO `%s` (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)", s.table),
+			mig.Version, mig.Name, mig.Checksum(), now.Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return err
+		}
+	}
+	s.applied[mig.Version] = record{version: mig.Version, name: mig.Name, checksum: mig.Checksum(), appliedAt: now}
+
+	return nil
+}
+
+func (s *session) down(version uint64) error {
+	mig := s.find(version)
+	if mig == nil {
+		return fmt.Errorf("migration: no file for applied version %d", version)
+	}
+	if !mig.HasDown() {
+		return fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+	}
+
+	if err := s.exec(fmt.Sprintf("down %d_%s", mig.Version, mig.Name), mig.Down); err != nil {
+		return fmt.Errorf("migration %d_%s: %v", mig.Version, mig.Name, err)
+	}
+
+	if !s.dryRun {
+		_, err := s.conn.ExecContext(s.ctx, fmt.Sprintf("DELETE FROM `%s` WHERE version = ?", s.table), version)
+		if err != nil {
+			return err
+		}
+	}
+	delete(s.applied, version)
+
+	return nil
+}
+
+func (s *session) exec(title, script string) error {
+	statements := splitStatements(script)
+	if s.dryRun {
+		fmt.Fprintf(s.out, "-- %s\n", title)
+		for _, stmt := range statements {
+			fmt.Fprintf(s.out, "%s;\n", stmt)
+		}
+		return nil
+	}
+
+	for _, stmt := range statements {
+		if _, err := s.conn.ExecContext(s.ctx, stmt); err != nil {
+			return err
+		}
+	}
+	log.New(log.InfoLevelLog, "migration", "applied ", title)
+
+	return nil
+}
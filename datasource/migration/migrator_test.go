@@ -0,0 +1,235 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maps90/go-core/layout"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// locks emulates the mysql named lock functions on sqlite connections,
+// GET_LOCK does not wait for a lock held by another connection
+var locks = struct {
+	sync.Mutex
+	owners map[string]int64
+	conns  int64
+}{owners: make(map[string]int64)}
+
+func init() {
+	sql.Register("sqlite3_migration", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			id := atomic.AddInt64(&locks.conns, 1)
+			if err := conn.RegisterFunc("GET_LOCK", func(name string, timeout int64) int64 {
+				locks.Lock()
+				defer locks.Unlock()
+				if owner, ok := locks.owners[name]; ok && owner != id {
+					return 0
+				}
+				locks.owners[name] = id
+				return 1
+			}, false); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("RELEASE_LOCK", func(name string) int64 {
+				locks.Lock()
+				defer locks.Unlock()
+				if locks.owners[name] != id {
+					return 0
+				}
+				delete(locks.owners, name)
+				return 1
+			}, false)
+		},
+	})
+}
+
+func openDB(t *testing.T) *sql.DB {
+	dir, _ := ioutil.TempDir("", "migration")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := sql.Open("sqlite3_migration", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var files = memLoader{
+	{Name: "0001_create_users.up.sql", Content: []byte("CREATE TABLE users (id INT);")},
+	{Name: "0001_create_users.down.sql", Content: []byte("DROP TABLE users;")},
+	{Name: "0002_create_orders.up.sql", Content: []byte("CREATE TABLE orders (id INT); CREATE TABLE items (id INT);")},
+	{Name: "0002_create_orders.down.sql", Content: []byte("DROP TABLE items; DROP TABLE orders;")},
+}
+
+func tables(t *testing.T, db *sql.DB) string {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func applied(t *testing.T, m *Migrator) []uint64 {
+	list, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versions []uint64
+	for _, st := range list {
+		if st.Applied {
+			versions = append(versions, st.Version)
+		}
+	}
+	return versions
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	db := openDB(t)
+	m := New(db, files)
+
+	if versions := applied(t, m); len(versions) != 0 {
+		t.Errorf("fresh database should have nothing applied, got %v", versions)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tables(t, db); got != "items,orders,users" {
+		t.Errorf("up should create all tables, got %q", got)
+	}
+	if versions := applied(t, m); len(versions) != 2 {
+		t.Errorf("both migrations should be applied, got %v", versions)
+	}
+	if err := m.Up(); err != nil {
+		t.Errorf("up without pending migrations should do nothing, got %v", err)
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tables(t, db); got != "users" {
+		t.Errorf("down should roll back the last migration, got %q", got)
+	}
+
+	if err := m.To(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.To(0); err != nil {
+		t.Fatal(err)
+	}
+	if got := tables(t, db); got != "" {
+		t.Errorf("to 0 should roll back everything, got %q", got)
+	}
+	if err := m.To(3); err == nil {
+		t.Error("unknown version should fail")
+	}
+}
+
+func TestMigratorUpNewerApplied(t *testing.T) {
+	db := openDB(t)
+	if err := New(db, files).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// an older release only knows the first migration
+	m := New(db, files[:2])
+	if err := m.Up(); err != nil {
+		t.Fatalf("up should keep unknown newer versions, got %v", err)
+	}
+	if got := tables(t, db); got != "items,orders,users" {
+		t.Errorf("up should not roll back, got %q", got)
+	}
+	if versions := applied(t, m); len(versions) != 2 {
+		t.Errorf("both versions should stay applied, got %v", versions)
+	}
+}
+
+func TestMigratorChecksum(t *testing.T) {
+	db := openDB(t)
+	if err := New(db, files).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := append(memLoader{}, files...)
+	edited[0] = layout.File{Name: "0001_create_users.up.sql", Content: []byte("CREATE TABLE users (id BIGINT);")}
+	m := New(db, edited)
+
+	if err := m.Up(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("edited migration should fail with ErrChecksum, got %v", err)
+	}
+	list, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !list[0].Dirty || list[1].Dirty {
+		t.Errorf("only the edited migration should be dirty, got %+v", list)
+	}
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	db := openDB(t)
+	m := New(db, files)
+	var out bytes.Buffer
+	m.SetDryRun(true)
+	m.SetOutput(&out)
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tables(t, db); got != "" {
+		t.Errorf("dry run should not create tables, got %q", got)
+	}
+	if !strings.Contains(out.String(), "-- up 2_create_orders\nCREATE TABLE orders (id INT);\n") {
+		t.Errorf("dry run should print the statements, got %q", out.String())
+	}
+}
+
+func TestMigratorLocked(t *testing.T) {
+	db := openDB(t)
+	m := New(db, files)
+	m.SetLockName("test:locked")
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "SELECT GET_LOCK('test:locked', 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(); err != ErrLocked {
+		t.Errorf("up should fail while another instance migrates, got %v", err)
+	}
+	if got := tables(t, db); got != "" {
+		t.Errorf("nothing should be applied without the lock, got %q", got)
+	}
+
+	conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK('test:locked')")
+	if err := m.Up(); err != nil {
+		t.Errorf("up should run once the lock is released, got %v", err)
+	}
+	if locks.owners["test:locked"] != 0 {
+		t.Error("lock should be released after migrating")
+	}
+}
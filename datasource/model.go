@@ -0,0 +1,91 @@
+package datasource
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrStaleObject is returned when updating a Versioned model that was changed
+// since it was read
+var ErrStaleObject = StaleObjectError{}
+
+// StaleObjectError is the type of ErrStaleObject
+type StaleObjectError struct{}
+
+func (StaleObjectError) Error() string {
+	return "datasource: stale object, it was modified by another update"
+}
+
+// StatusCode is used by middleware.ErrorHandler to respond 409 Conflict
+func (StaleObjectError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// Model is an embeddable base with a ULID primary key generated on create:
+//
+//	type Order struct {
+//		datasource.Model
+//		datasource.SoftDelete
+//		datasource.Versioned
+//		Total int
+//	}
+type Model struct {
+	ID        string    `gorm:"primary_key;type:char(26)" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *Model) BeforeCreate(scope *gorm.Scope) error {
+	if m.ID == "" {
+		return scope.SetColumn("ID", NewULID())
+	}
+	return nil
+}
+
+// UUIDModel works like Model with a time ordered UUID primary key
+type UUIDModel struct {
+	ID        string    `gorm:"primary_key;type:char(36)" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *UUIDModel) BeforeCreate(scope *gorm.Scope) error {
+	if m.ID == "" {
+		return scope.SetColumn("ID", NewUUID())
+	}
+	return nil
+}
+
+// SoftDelete makes gorm Delete set deleted_at and excludes deleted rows from
+// queries, use Unscoped to include them
+type SoftDelete struct {
+	DeletedAt *time.Time `sql:"index" json:"deleted_at,omitempty"`
+}
+
+// Versioned adds optimistic locking, updates only apply when the version is
+// unchanged since the row was read and return ErrStaleObject otherwise.
+// Models embedding it must not define their own BeforeUpdate or AfterUpdate,
+// UpdateColumn and batch updates bypass the check.
+type Versioned struct {
+	Version int64 `gorm:"not null" json:"version"`
+}
+
+func (v *Versioned) BeforeUpdate(scope *gorm.Scope) error {
+	if scope.PrimaryKeyZero() {
+		return nil
+	}
+
+	scope.Search.Where(scope.QuotedTableName()+"."+scope.Quote("version")+" = ?", v.Version)
+	return scope.SetColumn("Version", v.Version+1)
+}
+
+func (v *Versioned) AfterUpdate(scope *gorm.Scope) error {
+	if scope.PrimaryKeyZero() || scope.DB().RowsAffected > 0 {
+		return nil
+	}
+
+	v.Version--
+	return ErrStaleObject
+}
@@ -0,0 +1,86 @@
+package datasource
+
+import (
+	"sort"
+	"testing"
+)
+
+type document struct {
+	Model
+	SoftDelete
+	Versioned
+	Title string
+}
+
+func TestIDs(t *testing.T) {
+	ulids := make([]string, 100)
+	uuids := make([]string, 100)
+	for i := range ulids {
+		ulids[i] = NewULID()
+		uuids[i] = NewUUID()
+	}
+
+	if !sort.StringsAreSorted(ulids) || !sort.StringsAreSorted(uuids) {
+		t.Error("ids should be ordered")
+	}
+	if len(ulids[0]) != 26 || ulids[0] == ulids[1] {
+		t.Errorf("unexpected ulid %s", ulids[0])
+	}
+	if len(uuids[0]) != 36 || uuids[0][14] != '7' {
+		t.Errorf("unexpected uuid %s", uuids[0])
+	}
+}
+
+func TestModel(t *testing.T) {
+	ds := NewSqlite(":memory:")
+	defer ds.Close()
+	db := ds.Write()
+	db.AutoMigrate(&document{})
+
+	doc := &document{Title: "draft"}
+	if err := db.Create(doc).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.ID) != 26 || doc.CreatedAt.IsZero() {
+		t.Fatalf("id and timestamps should be set, got %+v", doc)
+	}
+
+	var stale document
+	db.First(&stale, "id = ?", doc.ID)
+
+	doc.Title = "published"
+	if err := db.Save(doc).Error; err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != 1 {
+		t.Errorf("version should be incremented, got %d", doc.Version)
+	}
+
+	stale.Title = "overwritten"
+	if err := db.Save(&stale).Error; err != ErrStaleObject {
+		t.Errorf("stale save should fail with ErrStaleObject, got %v", err)
+	}
+	if err := db.Model(&stale).Updates(map[string]interface{}{"title": "overwritten"}).Error; err != ErrStaleObject {
+		t.Errorf("stale update should fail with ErrStaleObject, got %v", err)
+	}
+	if err := db.Model(doc).Updates(map[string]interface{}{"title": "final"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var saved document
+	db.First(&saved, "id = ?", doc.ID)
+	if saved.Title != "final" || saved.Version != 2 {
+		t.Errorf("unexpected row %+v", saved)
+	}
+
+	db.Delete(doc)
+	var count int
+	db.Model(&document{}).Count(&count)
+	if count != 0 {
+		t.Error("deleted row should be hidden")
+	}
+	db.Unscoped().Model(&document{}).Count(&count)
+	if count != 1 {
+		t.Error("delete should be soft")
+	}
+}
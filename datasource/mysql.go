@@ -1,22 +1,25 @@
 package datasource
 
 import (
+	"context"
 	"os"
+	"sync"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	"github.com/maps90/go-core/log"
 )
 
-var (
-	dbReadConn  *gorm.DB
-	dbWriteConn *gorm.DB
-)
-
 type Mysql struct {
+	mu                         sync.Mutex
+	read, write                *gorm.DB
 	logMode                    bool
 	writerConn, readerConn     string
 	maxOpenConns, maxIdleConns int
+	connMaxLifetime            time.Duration
+	connMaxIdleTime            time.Duration
+	queryTimeout               time.Duration
+	queryLogger                *QueryLogger
 	err                        error
 }
 
@@ -28,6 +31,28 @@ func NewMysql(write, read string) *Mysql {
 	return m
 }
 
+// NewMysqlFromConfig returns Mysql for the write and read configs, pool
+// settings are taken from the write config
+func NewMysqlFromConfig(write, read *MysqlConfig) (*Mysql, error) {
+	writeDSN, err := write.DSN()
+	if err != nil {
+		return nil, err
+	}
+	readDSN, err := read.DSN()
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewMysql(writeDSN, readDSN)
+	m.SetOpenConn(write.MaxOpenConns)
+	m.SetIdleConn(write.MaxIdleConns)
+	m.SetConnMaxLifetime(write.ConnMaxLifetime)
+	m.SetConnMaxIdleTime(write.ConnMaxIdleTime)
+	m.SetQueryTimeout(write.QueryTimeout)
+
+	return m, nil
+}
+
 func (d *Mysql) Init() {
 	d.Read()
 	d.Write()
@@ -41,6 +66,12 @@ func (d *Mysql) SetDebug(debug bool) {
 	d.logMode = debug
 }
 
+// SetQueryLogger logs queries through go-core log instead of gorm's stdout
+// logger, SetDebug makes it log every query
+func (d *Mysql) SetQueryLogger(q *QueryLogger) {
+	d.queryLogger = q
+}
+
 func (d *Mysql) SetOpenConn(oc int) {
 	d.maxOpenConns = oc
 }
@@ -49,30 +80,63 @@ func (d *Mysql) SetIdleConn(ic int) {
 	d.maxIdleConns = ic
 }
 
+func (d *Mysql) SetConnMaxLifetime(t time.Duration) {
+	d.connMaxLifetime = t
+}
+
+func (d *Mysql) SetConnMaxIdleTime(t time.Duration) {
+	d.connMaxIdleTime = t
+}
+
+// SetQueryTimeout sets the default timeout of ReadCtx and WriteCtx, zero
+// only cancels on the context
+func (d *Mysql) SetQueryTimeout(t time.Duration) {
+	d.queryTimeout = t
+}
+
 func (d *Mysql) Write() *gorm.DB {
-	if dbWriteConn == nil {
-		dbWriteConn = d.createMysqlConn(d.writerConn)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.write == nil {
+		d.write = d.createMysqlConn(d.writerConn)
 	}
 
-	return dbWriteConn
+	return d.write
 }
 
 func (d *Mysql) Read() *gorm.DB {
-	if dbReadConn == nil {
-		dbReadConn = d.createMysqlConn(d.readerConn)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.read == nil {
+		d.read = d.createMysqlConn(d.readerConn)
 	}
 
-	return dbReadConn
+	return d.read
+}
+
+// ReadCtx returns Read bound to ctx, see Datasource
+func (d *Mysql) ReadCtx(ctx context.Context) *gorm.DB {
+	return withContext(ctx, d.Read(), d.queryTimeout)
+}
+
+// WriteCtx returns Write bound to ctx, see Datasource
+func (d *Mysql) WriteCtx(ctx context.Context) *gorm.DB {
+	return withContext(ctx, d.Write(), d.queryTimeout)
 }
 
 func (d *Mysql) createMysqlConn(descriptor string) *gorm.DB {
-	db, err := gorm.Open("mysql", descriptor)
+	db, err := gorm.Open("mysql", mysqlDriver, descriptor)
 	if err != nil {
-		log.New(log.ErrorLevelLog, "DB Connection Error: ", err.Error())
+		log.NewWithFields(log.ErrorLevelLog, "datasource", log.Fields{"dsn": RedactDSN(descriptor)}, "DB Connection Error: ", err.Error())
 		os.Exit(1)
 	}
 	db.DB().SetMaxIdleConns(d.maxIdleConns)
-	db.LogMode(d.logMode)
+	db.DB().SetConnMaxLifetime(d.connMaxLifetime)
+	db.DB().SetConnMaxIdleTime(d.connMaxIdleTime)
+	setLogger(db, d.logMode, d.queryLogger)
+	registerContextCallbacks(db)
 	db.DB().SetMaxOpenConns(d.maxOpenConns)
 	return db
 }
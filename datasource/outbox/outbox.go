@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// Table stores pending messages
+	Table = "outbox_messages"
+	// DeadLetterTable stores messages that failed MaxAttempts times
+	DeadLetterTable = "outbox_dead_letters"
+)
+
+// Event is published to Topic once the transaction that added it commits
+type Event struct {
+	Topic   string
+	Key     string
+	Payload []byte
+	Headers map[string]string
+}
+
+// Message is a stored event
+type Message struct {
+	ID            uint64    `gorm:"primary_key"`
+	Topic         string    `gorm:"size:255;not null"`
+	Key           string    `gorm:"size:255"`
+	Payload       []byte    `gorm:"type:blob"`
+	Headers       string    `gorm:"type:text"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string    `gorm:"type:text"`
+	CreatedAt     time.Time
+}
+
+func (Message) TableName() string {
+	return Table
+}
+
+// Header returns a header of the original event
+func (m *Message) Header(name string) string {
+	if m.Headers == "" {
+		return ""
+	}
+	headers := make(map[string]string)
+	json.Unmarshal([]byte(m.Headers), &headers)
+	return headers[name]
+}
+
+// DeadLetter is a message that could not be published
+type DeadLetter struct {
+	Message
+	FailedAt time.Time
+}
+
+func (DeadLetter) TableName() string {
+	return DeadLetterTable
+}
+
+// AutoMigrate creates the outbox and dead letter tables
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{}, &DeadLetter{}).Error
+}
+
+// Add stores event using tx, it must be the transaction that writes the
+// business data so both are committed or rolled back together
+func Add(tx *gorm.DB, event Event) error {
+	if event.Topic == "" {
+		return errors.New("outbox: event topic is required")
+	}
+
+	m := &Message{
+		Topic:         event.Topic,
+		Key:           event.Key,
+		Payload:       event.Payload,
+		NextAttemptAt: time.Now(),
+	}
+	if len(event.Headers) > 0 {
+		b, err := json.Marshal(event.Headers)
+		if err != nil {
+			return err
+		}
+		m.Headers = string(b)
+	}
+
+	return tx.Create(m).Error
+}
@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/log"
+)
+
+type order struct {
+	ID    uint
+	Total int
+}
+
+func setup(t *testing.T) *datasource.Sqlite {
+	log.Init("", false)
+
+	ds := datasource.NewSqlite(":memory:")
+	if err := AutoMigrate(ds.Write()); err != nil {
+		t.Fatal(err)
+	}
+	ds.Write().AutoMigrate(&order{})
+
+	return ds
+}
+
+func TestAddIsTransactional(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	tx := ds.Write().Begin()
+	tx.Create(&order{Total: 10})
+	if err := Add(tx, Event{Topic: "order.created", Payload: []byte(`{"total":10}`)}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	var count int
+	ds.Read().Model(&Message{}).Count(&count)
+	if count != 0 {
+		t.Errorf("rolled back event should not be stored, got %d", count)
+	}
+
+	if err := Add(ds.Write(), Event{}); err == nil {
+		t.Error("event without topic should be rejected")
+	}
+}
+
+func TestRelayPublishes(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	for _, topic := range []string{"order.created", "order.paid"} {
+		Add(ds.Write(), Event{Topic: topic, Key: "1", Headers: map[string]string{"trace": "abc"}})
+	}
+
+	pub := NewMemoryPublisher()
+	n, err := NewRelay(ds, pub).Process(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("should claim 2 messages, got %d", n)
+	}
+
+	messages := pub.Messages()
+	if len(messages) != 2 || messages[0].Topic != "order.created" || messages[1].Topic != "order.paid" {
+		t.Fatalf("unexpected published messages %+v", messages)
+	}
+	if messages[0].Header("trace") != "abc" {
+		t.Error("headers should be kept")
+	}
+
+	var count int
+	ds.Read().Model(&Message{}).Count(&count)
+	if count != 0 {
+		t.Errorf("published messages should be removed, got %d", count)
+	}
+}
+
+func TestRelayRetriesAndDeadLetters(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	Add(ds.Write(), Event{Topic: "order.created"})
+
+	pub := NewMemoryPublisher()
+	pub.SetFail(func(m *Message) error { return errors.New("broker down") })
+
+	relay := NewRelay(ds, pub)
+	relay.SetMaxAttempts(2)
+	relay.SetBackoff(0, 0)
+
+	relay.Process(context.Background())
+
+	var m Message
+	ds.Read().First(&m)
+	if m.Attempts != 1 || m.LastError != "broker down" {
+		t.Errorf("failed attempt should be recorded, got %+v", m)
+	}
+
+	relay.Process(context.Background())
+
+	var dead []DeadLetter
+	ds.Read().Find(&dead)
+	if len(dead) != 1 || dead[0].Topic != "order.created" || dead[0].Attempts != 2 {
+		t.Fatalf("message should be dead lettered, got %+v", dead)
+	}
+
+	var count int
+	ds.Read().Model(&Message{}).Count(&count)
+	if count != 0 {
+		t.Errorf("dead lettered message should leave the outbox, got %d", count)
+	}
+}
+
+func TestRelayCancelled(t *testing.T) {
+	ds := setup(t)
+	defer ds.Close()
+
+	Add(ds.Write(), Event{Topic: "order.created"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pub := NewMemoryPublisher()
+	pub.SetFail(func(m *Message) error {
+		cancel()
+		return ctx.Err()
+	})
+
+	relay := NewRelay(ds, pub)
+	relay.SetMaxAttempts(1)
+	if _, err := relay.Process(ctx); err != context.Canceled {
+		t.Errorf("cancelled publish should return the context error, got %v", err)
+	}
+
+	var m Message
+	ds.Read().First(&m)
+	if m.ID == 0 || m.Attempts != 0 || m.LastError != "" {
+		t.Errorf("cancelled publish should not count as an attempt, got %+v", m)
+	}
+	var dead int
+	ds.Read().Model(&DeadLetter{}).Count(&dead)
+	if dead != 0 {
+		t.Errorf("cancelled publish should not dead letter, got %d", dead)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := NewRelay(nil, nil)
+	r.SetBackoff(time.Second, 5*time.Second)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, d := range expected {
+		if got := r.backoff(i + 1); got != d {
+			t.Errorf("attempt %d should wait %s, got %s", i+1, d, got)
+		}
+	}
+}
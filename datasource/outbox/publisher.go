@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// Publisher delivers messages to a broker. Messages are delivered at least
+// once, consumers should deduplicate by ID.
+type Publisher interface {
+	Publish(ctx context.Context, m *Message) error
+}
+
+// MemoryPublisher keeps published messages in memory, for tests
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+	fail     func(m *Message) error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return new(MemoryPublisher)
+}
+
+// SetFail makes Publish return the error of fn, nil publishes normally
+func (p *MemoryPublisher) SetFail(fn func(m *Message) error) {
+	p.mu.Lock()
+	p.fail = fn
+	p.mu.Unlock()
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, m *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fail != nil {
+		if err := p.fail(m); err != nil {
+			return err
+		}
+	}
+	p.messages = append(p.messages, *m)
+
+	return nil
+}
+
+// Messages returns the published messages in order
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Message(nil), p.messages...)
+}
@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+	"github.com/maps90/go-core/log"
+)
+
+// Relay polls the outbox and publishes pending messages. Several relays may
+// run against the same database, on mysql and postgres rows are claimed with
+// FOR UPDATE SKIP LOCKED so each message is handled by one relay at a time.
+type Relay struct {
+	ds          datasource.Datasource
+	publisher   Publisher
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+func NewRelay(ds datasource.Datasource, publisher Publisher) *Relay {
+	return &Relay{
+		ds:          ds,
+		publisher:   publisher,
+		batchSize:   100,
+		interval:    time.Second,
+		maxAttempts: 10,
+		minBackoff:  time.Second,
+		maxBackoff:  10 * time.Minute,
+	}
+}
+
+// SetBatchSize sets how many messages are claimed per poll, defaults to 100
+func (r *Relay) SetBatchSize(size int) {
+	r.batchSize = size
+}
+
+// SetInterval sets the wait between polls when the outbox is empty,
+// defaults to 1s
+func (r *Relay) SetInterval(interval time.Duration) {
+	r.interval = interval
+}
+
+// SetMaxAttempts sets how many times a message is tried before it is moved
+// to the dead letter table, defaults to 10
+func (r *Relay) SetMaxAttempts(attempts int) {
+	r.maxAttempts = attempts
+}
+
+// SetBackoff sets the retry delay, doubled after each failed attempt from
+// min up to max, defaults to 1s and 10m
+func (r *Relay) SetBackoff(min, max time.Duration) {
+	r.minBackoff = min
+	r.maxBackoff = max
+}
+
+// Run processes the outbox until ctx is done
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.Process(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.New(log.ErrorLevelLog, "outbox", "process: ", err.Error())
+		}
+
+		if n < r.batchSize || err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(r.interval):
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Process claims and publishes one batch of due messages, it returns the
+// number of messages claimed
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	tx := r.ds.Write().Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	messages, err := r.claim(tx)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for i := range messages {
+		if err := r.publish(ctx, tx, &messages[i]); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return len(messages), tx.Commit().Error
+}
+
+func (r *Relay) claim(tx *gorm.DB) ([]Message, error) {
+	q := tx.Where("next_attempt_at <= ?", time.Now()).Order("id").Limit(r.batchSize)
+	switch tx.Dialect().GetName() {
+	case "mysql", "postgres":
+		q = q.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED")
+	}
+
+	var messages []Message
+	err := q.Find(&messages).Error
+
+	return messages, err
+}
+
+// publish sends m and removes it from the outbox, failures are rescheduled
+// or dead lettered. Only database errors and the error of ctx are returned,
+// a publish cut by ctx is not counted as an attempt and the batch is rolled
+// back.
+func (r *Relay) publish(ctx context.Context, tx *gorm.DB, m *Message) error {
+	err := r.publisher.Publish(ctx, m)
+	if err == nil {
+		return tx.Delete(m).Error
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	m.Attempts++
+	m.LastError = err.Error()
+	fields := log.Fields{"id": m.ID, "topic": m.Topic, "attempts": m.Attempts}
+
+	if m.Attempts >= r.maxAttempts {
+		log.NewWithFields(log.ErrorLevelLog, "outbox", fields, "dead letter: ", err.Error())
+		dead := &DeadLetter{Message: *m, FailedAt: time.Now()}
+		if err := tx.Create(dead).Error; err != nil {
+			return err
+		}
+		return tx.Delete(m).Error
+	}
+
+	log.NewWithFields(log.WarnLevelLog, "outbox", fields, "publish: ", err.Error())
+	m.NextAttemptAt = time.Now().Add(r.backoff(m.Attempts))
+
+	return tx.Model(m).Updates(map[string]interface{}{
+		"attempts":        m.Attempts,
+		"last_error":      m.LastError,
+		"next_attempt_at": m.NextAttemptAt,
+	}).Error
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.minBackoff
+	for i := 1; i < attempts && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,145 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/log"
+)
+
+var commentPattern = regexp.MustCompile(`[^\w\-.:]`)
+
+// QueryLogger writes gorm queries through go-core log. Only queries slower
+// than the threshold are logged unless debug is enabled, a zero threshold
+// logs every query.
+type QueryLogger struct {
+	topic         string
+	slowThreshold time.Duration
+	debug         bool
+	showParams    bool
+	comment       bool
+	requestID     string
+}
+
+func NewQueryLogger(slowThreshold time.Duration) *QueryLogger {
+	return &QueryLogger{
+		topic:         "sql",
+		slowThreshold: slowThreshold,
+	}
+}
+
+// SetTopic sets the log topic, defaults to "sql"
+func (q *QueryLogger) SetTopic(topic string) {
+	q.topic = topic
+}
+
+// SetDebug logs every query regardless of the threshold
+func (q *QueryLogger) SetDebug(debug bool) {
+	q.debug = debug
+}
+
+// ShowParams logs bound parameters instead of redacting them
+func (q *QueryLogger) ShowParams(show bool) {
+	q.showParams = show
+}
+
+// SetComment appends /* request_id=... */ to queries made through WithContext
+func (q *QueryLogger) SetComment(comment bool) {
+	q.comment = comment
+}
+
+// WithContext returns a copy of db whose queries are logged with the request
+// id found in ctx, see middleware.RequestID. With comment enabled the id is
+// also appended to each query through the gorm:*_option settings, so setting
+// e.g. gorm:query_option afterwards replaces the comment.
+func (q *QueryLogger) WithContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	id := log.RequestID(ctx)
+	if id == "" {
+		return db
+	}
+
+	child := *q
+	child.requestID = id
+
+	// Debug clones db with logging enabled, the logger stays on the copy
+	tx := db.Debug()
+	tx.SetLogger(&child)
+	if q.comment {
+		c := fmt.Sprintf("/* request_id=%s */", commentPattern.ReplaceAllString(id, ""))
+		tx = tx.Set("gorm:query_option", c).
+			Set("gorm:insert_option", c).
+			Set("gorm:update_option", c).
+			Set("gorm:delete_option", c)
+	}
+
+	return tx
+}
+
+// Print implements gorm logger
+func (q *QueryLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		return
+	}
+
+	fields := log.Fields{"caller": values[1]}
+	if q.requestID != "" {
+		fields["request_id"] = q.requestID
+	}
+
+	if values[0] != "sql" || len(values) < 5 {
+		// gorm reports errors and its own messages as "log"
+		log.NewWithFields(log.WarnLevelLog, q.topic, fields, values[2:]...)
+		return
+	}
+
+	duration, _ := values[2].(time.Duration)
+	slow := q.slowThreshold > 0 && duration >= q.slowThreshold
+	if !q.debug && !slow && q.slowThreshold > 0 {
+		return
+	}
+
+	fields["duration_ms"] = float64(duration) / float64(time.Millisecond)
+	fields["sql"] = tagPattern.ReplaceAllString(fmt.Sprint(values[3]), "")
+	if len(values) > 5 {
+		fields["rows"] = values[5]
+	}
+	if vars, ok := values[4].([]interface{}); ok && len(vars) > 0 {
+		fields["params"] = q.params(vars)
+	}
+
+	if slow {
+		fields["slow"] = true
+		log.NewWithFields(log.WarnLevelLog, q.topic, fields, "slow query")
+		return
+	}
+	log.NewWithFields(log.InfoLevelLog, q.topic, fields, "query")
+}
+
+func (q *QueryLogger) params(vars []interface{}) []string {
+	params := make([]string, len(vars))
+	for i, v := range vars {
+		if !q.showParams {
+			params[i] = "***"
+			continue
+		}
+
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		if !rv.IsValid() {
+			params[i] = "NULL"
+			continue
+		}
+		switch val := rv.Interface().(type) {
+		case time.Time:
+			params[i] = val.Format("2006-01-02 15:04:05")
+		case []byte:
+			params[i] = string(val)
+		default:
+			params[i] = fmt.Sprintf("%v", val)
+		}
+	}
+	return params
+}
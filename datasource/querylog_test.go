@@ -0,0 +1,100 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maps90/go-core/log"
+)
+
+func TestQueryLoggerParams(t *testing.T) {
+	q := NewQueryLogger(time.Second)
+	name := "gocore"
+	vars := []interface{}{1, &name, nil, []byte("raw")}
+
+	for _, p := range q.params(vars) {
+		if p != "***" {
+			t.Errorf("params should be redacted by default, got %q", p)
+		}
+	}
+
+	q.ShowParams(true)
+	params := q.params(vars)
+	expected := []string{"1", "gocore", "NULL", "raw"}
+	for i := range expected {
+		if params[i] != expected[i] {
+			t.Errorf("param %d should be %q, got %q", i, expected[i], params[i])
+		}
+	}
+}
+
+func queryEntries(t *testing.T, fn func()) []map[string]interface{} {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetLevel(log.DebugLevelLog)
+	defer log.SetLevel(log.InfoLevelLog)
+
+	fn()
+	log.SetOutput(os.Stderr)
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid entry %q: %v", line, err)
+		}
+		if entry["topic"] == "sql" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func TestQueryLoggerPrint(t *testing.T) {
+	ds := NewSqlite(":memory:")
+	defer ds.Close()
+	ds.Write().AutoMigrate(&note{})
+
+	q := NewQueryLogger(0)
+	q.SetComment(true)
+	ctx := log.WithRequestID(context.Background(), "req-1")
+
+	entries := queryEntries(t, func() {
+		q.WithContext(ds.Read(), ctx).Where("body = ?", "secret").Find(&[]note{})
+		ds.Read().Find(&[]note{})
+	})
+	if len(entries) != 1 {
+		t.Fatalf("only queries made through WithContext should be logged, got %v", entries)
+	}
+
+	e := entries[0]
+	if e["msg"] != "query" || e["level"] != "info" || e["request_id"] != "req-1" {
+		t.Errorf("unexpected entry %v", e)
+	}
+	if sql, _ := e["sql"].(string); !strings.Contains(sql, `"notes"`) || !strings.Contains(sql, "/* request_id=req-1 */") {
+		t.Errorf("query should be logged with its comment, got %v", e["sql"])
+	}
+	if params, _ := e["params"].([]interface{}); len(params) != 1 || params[0] != "***" {
+		t.Errorf("params should be redacted, got %v", e["params"])
+	}
+
+	q = NewQueryLogger(time.Nanosecond)
+	entries = queryEntries(t, func() {
+		q.WithContext(ds.Read(), ctx).Find(&[]note{})
+		q.WithContext(ds.Read(), ctx).Find(&[]struct{ Missing int }{})
+	})
+	if len(entries) != 3 {
+		t.Fatalf("should log both slow queries and the error, got %v", entries)
+	}
+	if entries[0]["msg"] != "slow query" || entries[0]["slow"] != true || entries[0]["level"] != "warning" {
+		t.Errorf("query over the threshold should be slow, got %v", entries[0])
+	}
+	if msg, _ := entries[1]["msg"].(string); !strings.HasPrefix(msg, "no such table") || entries[1]["request_id"] != "req-1" {
+		t.Errorf("gorm errors should be logged as warnings, got %v", entries[1])
+	}
+}
@@ -0,0 +1,47 @@
+package datasource
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Datasource)
+)
+
+// Register makes ds available under name, registering a name again
+// replaces the previous datasource
+func Register(name string, ds Datasource) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[name] = ds
+}
+
+// Get returns the datasource registered under name
+func Get(name string) (Datasource, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	ds, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("datasource: %s is not registered", name)
+	}
+	return ds, nil
+}
+
+// Names returns the registered names in order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
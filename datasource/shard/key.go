@@ -0,0 +1,146 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrNoShard is returned when a key does not map to any shard
+var ErrNoShard = errors.New("shard: no shard for key")
+
+// KeyFunc returns the name of the shard holding key
+type KeyFunc func(key interface{}) (string, error)
+
+// HashMod spreads keys evenly over shards with fnv-1a, adding a shard moves
+// most keys so prefer Range or Lookup for clusters that grow
+func HashMod(shards ...string) KeyFunc {
+	return func(key interface{}) (string, error) {
+		if len(shards) == 0 {
+			return "", ErrNoShard
+		}
+		h := fnv.New32a()
+		h.Write([]byte(fmt.Sprint(key)))
+		return shards[h.Sum32()%uint32(len(shards))], nil
+	}
+}
+
+// Range maps integer keys from Min up to but excluding Max to Shard
+type Range struct {
+	Min, Max int64
+	Shard    string
+}
+
+// Ranges maps keys through a range table
+func Ranges(ranges ...Range) KeyFunc {
+	table := append([]Range(nil), ranges...)
+	sort.Slice(table, func(i, j int) bool { return table[i].Min < table[j].Min })
+
+	return func(key interface{}) (string, error) {
+		k, err := toInt(key)
+		if err != nil {
+			return "", err
+		}
+		i := sort.Search(len(table), func(i int) bool { return table[i].Max > k })
+		if i == len(table) || table[i].Min > k {
+			return "", ErrNoShard
+		}
+		return table[i].Shard, nil
+	}
+}
+
+// Directory stores the shard of each key
+type Directory interface {
+	Lookup(key interface{}) (shard string, ok bool, err error)
+}
+
+// Lookup maps keys through dir, keys missing from dir are passed to
+// fallback when it is not nil, e.g. to place new customers by HashMod
+func Lookup(dir Directory, fallback KeyFunc) KeyFunc {
+	return func(key interface{}) (string, error) {
+		shard, ok, err := dir.Lookup(key)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return shard, nil
+		}
+		if fallback != nil {
+			return fallback(key)
+		}
+		return "", ErrNoShard
+	}
+}
+
+// MapDirectory is an in-memory Directory keyed by fmt.Sprint(key)
+type MapDirectory map[string]string
+
+func (d MapDirectory) Lookup(key interface{}) (string, bool, error) {
+	shard, ok := d[fmt.Sprint(key)]
+	return shard, ok, nil
+}
+
+// TableDirectory reads the shard of each key from a table, found entries
+// are cached since keys rarely move
+type TableDirectory struct {
+	db          *gorm.DB
+	table       string
+	keyColumn   string
+	shardColumn string
+	cache       sync.Map
+}
+
+// NewTableDirectory returns a Directory reading shardColumn of the row whose
+// keyColumn matches the key
+func NewTableDirectory(db *gorm.DB, table, keyColumn, shardColumn string) *TableDirectory {
+	return &TableDirectory{
+		db:          db,
+		table:       table,
+		keyColumn:   keyColumn,
+		shardColumn: shardColumn,
+	}
+}
+
+func (d *TableDirectory) Lookup(key interface{}) (string, bool, error) {
+	cacheKey := fmt.Sprint(key)
+	if shard, ok := d.cache.Load(cacheKey); ok {
+		return shard.(string), true, nil
+	}
+
+	scope := d.db.NewScope(nil)
+	var shards []string
+	err := d.db.Table(d.table).
+		Where(scope.Quote(d.keyColumn)+" = ?", key).
+		Limit(1).
+		Pluck(scope.Quote(d.shardColumn), &shards).Error
+	if err != nil || len(shards) == 0 {
+		return "", false, err
+	}
+
+	d.cache.Store(cacheKey, shards[0])
+	return shards[0], true, nil
+}
+
+// Forget drops a cached key, call it after moving the key to another shard
+func (d *TableDirectory) Forget(key interface{}) {
+	d.cache.Delete(fmt.Sprint(key))
+}
+
+func toInt(key interface{}) (int64, error) {
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	case reflect.String:
+		return strconv.ParseInt(v.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("shard: key %v is not an integer", key)
+}
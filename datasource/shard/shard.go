@@ -0,0 +1,159 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+)
+
+// Router picks shards among datasources of the datasource registry, e.g.
+//
+//	datasource.Register("customers-1", mysql1)
+//	datasource.Register("customers-2", mysql2)
+//	customers := shard.New(shard.HashMod("customers-1", "customers-2"), "customers-1", "customers-2")
+//
+//	ds, err := customers.ShardFor(customerID)
+//	ds.Write().Create(&order)
+type Router struct {
+	key         KeyFunc
+	shards      []string
+	concurrency int
+}
+
+// New returns Router for the named shards, every name returned by key must
+// be one of them
+func New(key KeyFunc, shards ...string) *Router {
+	return &Router{
+		key:         key,
+		shards:      shards,
+		concurrency: 4,
+	}
+}
+
+// SetConcurrency sets how many shards are queried at once by Each and
+// Gather, defaults to 4
+func (r *Router) SetConcurrency(n int) {
+	r.concurrency = n
+}
+
+// Shards returns the shard names
+func (r *Router) Shards() []string {
+	return append([]string(nil), r.shards...)
+}
+
+// ShardFor returns the datasource holding key
+func (r *Router) ShardFor(key interface{}) (datasource.Datasource, error) {
+	name, err := r.key(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range r.shards {
+		if s == name {
+			return datasource.Get(name)
+		}
+	}
+	return nil, ErrNoShard
+}
+
+// Each calls fn for every shard with bounded concurrency. The first error
+// cancels the ctx passed to the other calls and is returned.
+func (r *Router) Each(ctx context.Context, fn func(ctx context.Context, name string, ds datasource.Datasource) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := r.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for _, name := range r.shards {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ds, err := datasource.Get(name)
+			if err == nil {
+				err = fn(ctx, name, ds)
+			}
+			if err != nil {
+				fail(err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// Gather runs query against the read connection of every shard and appends
+// the rows to dest, a pointer to a slice, in shard order. The queries run
+// with ctx, see Datasource.ReadCtx, a failing shard aborts the others. Sort dest
+// afterwards when a global order is needed, e.g.
+//
+//	var orders []Order
+//	err := customers.Gather(ctx, &orders, func(db *gorm.DB) *gorm.DB {
+//		return db.Where("status = ?", "unpaid")
+//	})
+func (r *Router) Gather(ctx context.Context, dest interface{}, query func(db *gorm.DB) *gorm.DB) error {
+	out := reflect.ValueOf(dest)
+	if out.Kind() != reflect.Ptr || out.Elem().Kind() != reflect.Slice {
+		return errors.New("shard: dest must be a pointer to a slice")
+	}
+
+	results := make(map[string]reflect.Value, len(r.shards))
+	var mu sync.Mutex
+
+	err := r.Each(ctx, func(ctx context.Context, name string, ds datasource.Datasource) error {
+		rows := reflect.New(out.Elem().Type())
+		if err := query(ds.ReadCtx(ctx)).Find(rows.Interface()).Error; err != nil {
+			return err
+		}
+
+		mu.Lock()
+		results[name] = rows.Elem()
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	merged := out.Elem()
+	for _, name := range r.shards {
+		if rows, ok := results[name]; ok {
+			merged = reflect.AppendSlice(merged, rows)
+		}
+	}
+	out.Elem().Set(merged)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/datasource"
+)
+
+type order struct {
+	ID         uint
+	CustomerID int64
+}
+
+type directory struct {
+	Customer string
+	Shard    string
+}
+
+func TestKeyFuncs(t *testing.T) {
+	hash := HashMod("a", "b", "c")
+	first, _ := hash(42)
+	if again, _ := hash(42); again != first {
+		t.Error("hash mod should be stable")
+	}
+
+	ranges := Ranges(Range{Min: 1000, Max: 2000, Shard: "b"}, Range{Min: 0, Max: 1000, Shard: "a"})
+	for key, expected := range map[interface{}]string{0: "a", int64(999): "a", uint(1000): "b", "1500": "b"} {
+		if got, err := ranges(key); got != expected || err != nil {
+			t.Errorf("%v should map to %s, got %s %v", key, expected, got, err)
+		}
+	}
+	if _, err := ranges(2000); err != ErrNoShard {
+		t.Errorf("key out of range should fail, got %v", err)
+	}
+	if _, err := ranges("x"); err == nil {
+		t.Error("non integer key should fail")
+	}
+
+	lookup := Lookup(MapDirectory{"7": "b"}, func(interface{}) (string, error) { return "a", nil })
+	if got, _ := lookup(7); got != "b" {
+		t.Errorf("directory entry should win, got %s", got)
+	}
+	if got, _ := lookup(8); got != "a" {
+		t.Errorf("missing key should use fallback, got %s", got)
+	}
+	if _, err := Lookup(MapDirectory{}, nil)(8); err != ErrNoShard {
+		t.Errorf("missing key without fallback should fail, got %v", err)
+	}
+}
+
+func TestTableDirectory(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+	ds.Write().AutoMigrate(&directory{})
+	ds.Write().Create(&directory{Customer: "acme", Shard: "b"})
+
+	dir := NewTableDirectory(ds.Read(), "directories", "customer", "shard")
+	if shard, ok, err := dir.Lookup("acme"); shard != "b" || !ok || err != nil {
+		t.Errorf("should find acme on b, got %s %v %v", shard, ok, err)
+	}
+	if _, ok, err := dir.Lookup("globex"); ok || err != nil {
+		t.Errorf("globex should be missing, got %v %v", ok, err)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	names := []string{"test-shard-0", "test-shard-1", "test-shard-2"}
+	for _, name := range names {
+		ds := datasource.NewSqlite(":memory:")
+		defer ds.Close()
+		ds.Write().AutoMigrate(&order{})
+		datasource.Register(name, ds)
+	}
+
+	r := New(HashMod(names...), names...)
+	r.SetConcurrency(2)
+
+	for customer := int64(1); customer <= 30; customer++ {
+		ds, err := r.ShardFor(customer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds.Write().Create(&order{CustomerID: customer})
+	}
+
+	var orders []order
+	err := r.Gather(context.Background(), &orders, func(db *gorm.DB) *gorm.DB {
+		return db.Where("customer_id > ?", 10)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 20 {
+		t.Fatalf("should gather 20 orders, got %d", len(orders))
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CustomerID < orders[j].CustomerID })
+	if orders[0].CustomerID != 11 || orders[19].CustomerID != 30 {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+
+	boom := errors.New("boom")
+	err = r.Each(context.Background(), func(ctx context.Context, name string, ds datasource.Datasource) error {
+		if name == names[1] {
+			return boom
+		}
+		return nil
+	})
+	if err != boom {
+		t.Errorf("first error should be returned, got %v", err)
+	}
+
+	if _, err := New(HashMod("unknown"), names...).ShardFor(1); err != ErrNoShard {
+		t.Errorf("shard outside the router should fail, got %v", err)
+	}
+}
+
+func TestGatherCancel(t *testing.T) {
+	names := []string{"test-cancel-slow", "test-cancel-broken"}
+	slow := datasource.NewSqlite(":memory:")
+	defer slow.Close()
+	// counts for several seconds
+	slow.Write().Exec("CREATE VIEW orders AS WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000) SELECT x AS id, x AS customer_id FROM c")
+	datasource.Register(names[0], slow)
+	// has no orders table
+	broken := datasource.NewSqlite(":memory:")
+	defer broken.Close()
+	datasource.Register(names[1], broken)
+
+	r := New(HashMod(names...), names...)
+	r.SetConcurrency(2)
+
+	start := time.Now()
+	var orders []order
+	err := r.Gather(context.Background(), &orders, func(db *gorm.DB) *gorm.DB {
+		return db.Where("customer_id < ?", 0)
+	})
+	if err == nil || err == context.Canceled {
+		t.Errorf("error of the broken shard should be returned, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("failing shard should cancel the slow query, took %v", elapsed)
+	}
+}
@@ -0,0 +1,115 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/maps90/go-core/log"
+)
+
+var memoryDBs int64
+
+// Sqlite is a file or in-memory database for local development and tests,
+// reads and writes share the same connection pool
+type Sqlite struct {
+	mu           sync.Mutex
+	logMode      bool
+	path         string
+	conn         *gorm.DB
+	queryLogger  *QueryLogger
+	queryTimeout time.Duration
+	err          error
+}
+
+// NewSqlite returns sqlite datasource stored at path, use ":memory:" for an
+// in-memory database private to this datasource
+func NewSqlite(path string) *Sqlite {
+	s := new(Sqlite)
+	s.path = path
+
+	return s
+}
+
+func (d *Sqlite) Init() {
+	d.Write()
+}
+
+func (d *Sqlite) Error() error {
+	return d.err
+}
+
+func (d *Sqlite) SetDebug(debug bool) {
+	d.logMode = debug
+}
+
+func (d *Sqlite) SetQueryLogger(q *QueryLogger) {
+	d.queryLogger = q
+}
+
+// SetQueryTimeout sets the default timeout of ReadCtx and WriteCtx
+func (d *Sqlite) SetQueryTimeout(t time.Duration) {
+	d.queryTimeout = t
+}
+
+func (d *Sqlite) Write() *gorm.DB {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn == nil {
+		d.conn = d.createSqliteConn()
+	}
+
+	return d.conn
+}
+
+func (d *Sqlite) Read() *gorm.DB {
+	return d.Write()
+}
+
+func (d *Sqlite) ReadCtx(ctx context.Context) *gorm.DB {
+	return d.WriteCtx(ctx)
+}
+
+func (d *Sqlite) WriteCtx(ctx context.Context) *gorm.DB {
+	return withContext(ctx, d.Write(), d.queryTimeout)
+}
+
+// Close closes the database, an in-memory database is discarded
+func (d *Sqlite) Close() error {
+	if d.conn == nil {
+		return nil
+	}
+	err := d.conn.Close()
+	d.conn = nil
+	return err
+}
+
+func (d *Sqlite) createSqliteConn() *gorm.DB {
+	descriptor := d.path
+	if descriptor == ":memory:" {
+		// shared cache keeps every pooled connection on the same database
+		descriptor = fmt.Sprintf("file:gocore%d?mode=memory&cache=shared", atomic.AddInt64(&memoryDBs, 1))
+	} else if !strings.Contains(descriptor, "?") {
+		descriptor += "?_busy_timeout=5000"
+	}
+
+	db, err := gorm.Open("sqlite3", sqliteDriver, descriptor)
+	if err != nil {
+		d.err = err
+		log.New(log.ErrorLevelLog, "datasource", "DB Connection Error: ", err.Error())
+		// gorm returns its closed handle along with err, queries through it
+		// fail with err instead of panicking on a nil handle
+		db.Error = err
+		return db
+	}
+	// the database is dropped once its last connection is closed
+	db.DB().SetMaxIdleConns(1)
+	setLogger(db, d.logMode, d.queryLogger)
+	registerContextCallbacks(db)
+	return db
+}
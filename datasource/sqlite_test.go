@@ -0,0 +1,57 @@
+package datasource
+
+import "testing"
+
+type note struct {
+	ID   uint
+	Body string
+}
+
+func TestSqliteReadWrite(t *testing.T) {
+	var ds Datasource = NewSqlite(":memory:")
+	ds.Init()
+	if ds.Error() != nil {
+		t.Fatal(ds.Error())
+	}
+
+	if err := ds.Write().AutoMigrate(&note{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Write().Create(&note{Body: "hello"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var n note
+	if err := ds.Read().First(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n.Body != "hello" {
+		t.Errorf("read should see written row, got %q", n.Body)
+	}
+}
+
+func TestSqliteMemoryIsolated(t *testing.T) {
+	a := NewSqlite(":memory:")
+	b := NewSqlite(":memory:")
+	defer a.Close()
+	defer b.Close()
+
+	a.Write().AutoMigrate(&note{})
+	if b.Read().HasTable(&note{}) {
+		t.Error("in-memory databases should not share tables")
+	}
+}
+
+func TestSqliteOpenError(t *testing.T) {
+	ds := NewSqlite("/nonexistent/go-core/test.db")
+	ds.Init()
+	defer ds.Close()
+
+	if ds.Error() == nil {
+		t.Fatal("opening a missing directory should fail")
+	}
+	var notes []note
+	if err := ds.Read().Find(&notes).Error; err == nil {
+		t.Error("queries should fail with the open error instead of panicking")
+	}
+}
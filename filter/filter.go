@@ -0,0 +1,313 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+	"github.com/maps90/go-core/validation"
+)
+
+// Type of a filterable field, used to check and convert query values
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Float
+	Bool
+	Time
+)
+
+// Operator is a comparison used as filter[field][op]=value
+type Operator string
+
+const (
+	Eq   Operator = "eq"
+	Ne   Operator = "ne"
+	Gt   Operator = "gt"
+	Gte  Operator = "gte"
+	Lt   Operator = "lt"
+	Lte  Operator = "lte"
+	In   Operator = "in"
+	Like Operator = "like"
+	Null Operator = "null"
+)
+
+// MaxStringSize is the longest accepted string value
+var MaxStringSize = 255
+
+var (
+	paramPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+	intPattern   = regexp.MustCompile(`^-?\d+$`)
+	boolPattern  = regexp.MustCompile(`^(true|false|1|0)$`)
+
+	sqlOperators = map[Operator]string{
+		Eq:   "= ?",
+		Ne:   "<> ?",
+		Gt:   "> ?",
+		Gte:  ">= ?",
+		Lt:   "< ?",
+		Lte:  "<= ?",
+		In:   "IN (?)",
+		Like: "LIKE ?",
+	}
+
+	defaultOperators = map[Type][]Operator{
+		String: {Eq, Ne, In, Like, Null},
+		Int:    {Eq, Ne, Gt, Gte, Lt, Lte, In, Null},
+		Float:  {Eq, Ne, Gt, Gte, Lt, Lte, In, Null},
+		Bool:   {Eq, Ne, Null},
+		Time:   {Eq, Ne, Gt, Gte, Lt, Lte, In, Null},
+	}
+
+	// "!" escapes the same way in mysql and sqlite, unlike backslash
+	likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+)
+
+// Field is a whitelisted column, Column defaults to the query name and
+// Operators to every operator supported by Type
+type Field struct {
+	Column    string
+	Type      Type
+	Filter    bool
+	Sort      bool
+	Operators []Operator
+}
+
+// Schema maps query names to fields
+type Schema map[string]Field
+
+// Filterable is implemented by models exposing a filter whitelist
+type Filterable interface {
+	FilterSchema() Schema
+}
+
+// Errors maps a query parameter to its error message
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys))
+	for i, k := range keys {
+		msgs[i] = k + " " + e[k]
+	}
+	return strings.Join(msgs, ", ")
+}
+
+type condition struct {
+	column string
+	op     Operator
+	value  interface{}
+}
+
+type order struct {
+	column string
+	desc   bool
+}
+
+// Query is a parsed set of filters and sort orders
+type Query struct {
+	conditions []condition
+	orders     []order
+}
+
+// FromContext parses the request query string against the model schema
+func FromContext(c echo.Context, model Filterable) (*Query, error) {
+	return Parse(c.QueryParams(), model.FilterSchema())
+}
+
+// Parse turns filter[field][op]=value and sort=-field,field params into a
+// Query. Every invalid parameter is reported in the returned Errors.
+func Parse(values url.Values, schema Schema) (*Query, error) {
+	var (
+		q      = new(Query)
+		errs   = make(Errors)
+		valid  = validation.Validation{}
+		params = make([]string, 0, len(values))
+	)
+
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	for _, param := range params {
+		match := paramPattern.FindStringSubmatch(param)
+		if match == nil {
+			continue
+		}
+
+		name, op := match[1], Operator(match[2])
+		if op == "" {
+			op = Eq
+		}
+
+		field, ok := schema[name]
+		if !ok || !field.Filter {
+			errs[param] = "is not filterable"
+			continue
+		}
+		if !field.allows(op) {
+			errs[param] = fmt.Sprintf("does not support operator %s", op)
+			continue
+		}
+
+		value, err := field.convert(&valid, param, op, values.Get(param))
+		if err != "" {
+			errs[param] = err
+			continue
+		}
+		q.conditions = append(q.conditions, condition{column: field.column(name), op: op, value: value})
+	}
+
+	if s := values.Get("sort"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+
+			field, ok := schema[name]
+			if !ok || !field.Sort {
+				errs["sort"] = fmt.Sprintf("%s is not sortable", name)
+				break
+			}
+			q.orders = append(q.orders, order{column: field.column(name), desc: desc})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return q, nil
+}
+
+// Apply adds the filters as Where and the sort as Order clauses
+func (q *Query) Apply(db *gorm.DB) *gorm.DB {
+	scope := db.NewScope(nil)
+
+	for _, c := range q.conditions {
+		column := scope.Quote(c.column)
+		switch c.op {
+		case Null:
+			if c.value.(bool) {
+				db = db.Where(column + " IS NULL")
+			} else {
+				db = db.Where(column + " IS NOT NULL")
+			}
+		case Like:
+			db = db.Where(column+" LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(c.value.(string))+"%")
+		default:
+			db = db.Where(column+" "+sqlOperators[c.op], c.value)
+		}
+	}
+
+	for _, o := range q.orders {
+		if o.desc {
+			db = db.Order(scope.Quote(o.column) + " DESC")
+		} else {
+			db = db.Order(scope.Quote(o.column) + " ASC")
+		}
+	}
+
+	return db
+}
+
+func (f Field) column(name string) string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return name
+}
+
+func (f Field) allows(op Operator) bool {
+	ops := f.Operators
+	if len(ops) == 0 {
+		ops = defaultOperators[f.Type]
+	}
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// convert checks raw through the validation package and returns the typed
+// value, or the error message
+func (f Field) convert(valid *validation.Validation, key string, op Operator, raw string) (interface{}, string) {
+	valid.Clear()
+	if r := valid.Required(raw, key); !r.Ok {
+		return nil, r.Error.Message
+	}
+
+	if op == Null {
+		if r := valid.Match(raw, boolPattern, key); !r.Ok {
+			return nil, "must be true or false"
+		}
+		return raw == "true" || raw == "1", ""
+	}
+
+	if op == In {
+		parts := strings.Split(raw, ",")
+		values := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			v, err := f.convert(valid, key, Eq, strings.TrimSpace(part))
+			if err != "" {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, ""
+	}
+
+	switch f.Type {
+	case Int:
+		if r := valid.Match(raw, intPattern, key).Message("must be an integer"); !r.Ok {
+			return nil, r.Error.Message
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, "must be an integer"
+		}
+		return v, ""
+	case Float:
+		if r := valid.Float(raw, key); !r.Ok {
+			return nil, r.Error.Message
+		}
+		v, _ := strconv.ParseFloat(raw, 64)
+		return v, ""
+	case Bool:
+		if r := valid.Match(raw, boolPattern, key).Message("must be true or false"); !r.Ok {
+			return nil, r.Error.Message
+		}
+		return raw == "true" || raw == "1", ""
+	case Time:
+		format := time.RFC3339
+		if len(raw) == len("2006-01-02") {
+			format = "2006-01-02"
+		}
+		if r := valid.IsDate(raw, format, key); !r.Ok {
+			return nil, r.Error.Message
+		}
+		v, _ := time.Parse(format, raw)
+		return v, ""
+	default:
+		if r := valid.MaxSize(raw, MaxStringSize, key); !r.Ok {
+			return nil, r.Error.Message
+		}
+		return raw, ""
+	}
+}
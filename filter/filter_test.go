@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/maps90/go-core/datasource"
+)
+
+type invoice struct {
+	ID        int
+	Status    string
+	Amount    float64
+	Note      *string
+	CreatedAt time.Time
+}
+
+func (invoice) FilterSchema() Schema {
+	return Schema{
+		"id":         {Type: Int, Filter: true, Sort: true},
+		"status":     {Type: String, Filter: true, Operators: []Operator{Eq, In, Like}},
+		"amount":     {Type: Float, Filter: true, Sort: true},
+		"note":       {Type: String, Filter: true},
+		"created_at": {Type: Time, Filter: true, Sort: true},
+		"secret":     {Type: String},
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	values, _ := url.ParseQuery("filter[amount][gte]=abc&filter[status][gt]=x&filter[secret]=1&filter[id][in]=1,x&filter[created_at]=yesterday&sort=-secret")
+	_, err := Parse(values, invoice{}.FilterSchema())
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("should return Errors, got %v", err)
+	}
+
+	for _, param := range []string{"filter[amount][gte]", "filter[status][gt]", "filter[secret]", "filter[id][in]", "filter[created_at]", "sort"} {
+		if _, ok := errs[param]; !ok {
+			t.Errorf("%s should be reported", param)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	ds := datasource.NewSqlite(":memory:")
+	defer ds.Close()
+
+	db := ds.Write()
+	db.AutoMigrate(&invoice{})
+	note := "100%_off"
+	base := time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, o := range []invoice{
+		{Status: "paid", Amount: 150},
+		{Status: "paid", Amount: 50},
+		{Status: "void", Amount: 300, Note: &note},
+		{Status: "paid", Amount: 100},
+	} {
+		o.CreatedAt = base.AddDate(0, 0, i)
+		if err := db.Create(&o).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string][]int{
+		"filter[status]=paid&filter[amount][gte]=100&sort=-amount,id": {1, 4},
+		"filter[status][in]=paid,void&filter[amount][lt]=100":         {2},
+		"filter[note][null]=false":                                    {3},
+		"filter[status][like]=ai&sort=-created_at":                    {4, 2, 1},
+		"filter[created_at][gt]=2017-05-02&sort=id":                   {3, 4},
+	}
+	for qs, expected := range cases {
+		values, _ := url.ParseQuery(qs)
+		q, err := Parse(values, invoice{}.FilterSchema())
+		if err != nil {
+			t.Errorf("%s: %v", qs, err)
+			continue
+		}
+
+		var invoices []invoice
+		if err := q.Apply(ds.Read()).Find(&invoices).Error; err != nil {
+			t.Errorf("%s: %v", qs, err)
+			continue
+		}
+		if len(invoices) != len(expected) {
+			t.Errorf("%s: expected %v, got %d rows", qs, expected, len(invoices))
+			continue
+		}
+		for i, o := range invoices {
+			if o.ID != expected[i] {
+				t.Errorf("%s: expected %v, got row %d at %d", qs, expected, o.ID, i)
+			}
+		}
+	}
+
+	values, _ := url.ParseQuery("filter[note][like]=%25_")
+	q, _ := Parse(values, invoice{}.FilterSchema())
+	var invoices []invoice
+	q.Apply(ds.Read()).Find(&invoices)
+	if len(invoices) != 1 {
+		t.Errorf("like wildcards should be escaped, got %d rows", len(invoices))
+	}
+}
@@ -107,3 +107,9 @@ func (loader *bindataLoader) getDirTree(baseDir string) ([]string, error) {
 func FSLoader(path, extension string) Loader {
 	return &fsLoader{basePath: path, extension: extension}
 }
+
+// BindataLoader returns go-bindata template loader, assetDir and asset are
+// the AssetDir and Asset functions generated by go-bindata
+func BindataLoader(dir string, assetDir func(name string) ([]string, error), asset func(path string) ([]byte, error)) Loader {
+	return &bindataLoader{dir: dir, assetDir: assetDir, loaderFunc: asset}
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+// LevelRequest changes the level of Topic, "" for the default level. An empty
+// Level resets the topic to the default level and Revert, e.g. "15m",
+// overrides the SetAutoRevert duration, "0" keeps the change.
+type LevelRequest struct {
+	Topic  string `json:"topic" form:"topic"`
+	Level  string `json:"level" form:"level"`
+	Revert string `json:"revert" form:"revert"`
+}
+
+// LevelResponse is the default level and the levels set per topic
+type LevelResponse struct {
+	Level  string            `json:"level"`
+	Topics map[string]string `json:"topics"`
+}
+
+// LevelHandler shows the levels on GET and changes them on PUT or POST with
+// a LevelRequest, it must be mounted behind authentication, see
+// RouterSetup.SetLogAdmin
+func LevelHandler(c echo.Context) error {
+	if c.Request().Method != http.MethodGet {
+		req := new(LevelRequest)
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+		if err := changeLevel(req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	res := LevelResponse{Level: GetLevel().String(), Topics: map[string]string{}}
+	for topic, level := range TopicLevels() {
+		res.Topics[topic] = level.String()
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func changeLevel(req *LevelRequest) error {
+	levels.mu.RLock()
+	d := levels.autoRevert
+	levels.mu.RUnlock()
+
+	if req.Revert != "" {
+		revert, err := time.ParseDuration(req.Revert)
+		if err != nil {
+			return err
+		}
+		d = revert
+	}
+
+	if req.Level == "" {
+		if req.Topic == "" {
+			return errors.New("level is required")
+		}
+		ResetTopicLevel(req.Topic)
+		return nil
+	}
+
+	level, err := log.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	SetLevelFor(req.Topic, level, d)
+
+	Topic(DefaultTopic).With(Fields{"level_topic": req.Topic, "revert": d.String()}).Warnf("log level changed to %s", level)
+	return nil
+}
@@ -0,0 +1,246 @@
+package log
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Overflow is what an async logger does when its buffer is full
+type Overflow int
+
+const (
+	// Block waits for room in the buffer
+	Block Overflow = iota
+	// DropOldest drops the oldest buffered entry
+	DropOldest
+	// DropLowest drops the least severe entry, the oldest of them when
+	// several share the level, which may be the new entry
+	DropLowest
+)
+
+// ParseOverflow returns the policy named block, drop_oldest or drop_lowest
+func ParseOverflow(name string) (Overflow, error) {
+	switch name {
+	case "", "block":
+		return Block, nil
+	case "drop_oldest":
+		return DropOldest, nil
+	case "drop_lowest":
+		return DropLowest, nil
+	}
+	return Block, fmt.Errorf("log: unknown overflow policy %q", name)
+}
+
+// AsyncStats are the counters of the async buffer, also published with
+// expvar as gocore.log.async
+type AsyncStats struct {
+	Size    int               `json:"size"`
+	Queued  int               `json:"queued"`
+	Dropped uint64            `json:"dropped"`
+	Levels  map[string]uint64 `json:"dropped_by_level"`
+}
+
+var publishStats sync.Once
+
+// SetAsync writes entries from a goroutine through a buffer of size entries
+// so logging does not block on slow outputs, size 0 writes synchronously
+// again. Pending entries are flushed before fatal and panic entries and by
+// Flush and Close.
+func SetAsync(size int, overflow Overflow) {
+	var q *queue
+	if size > 0 {
+		q = newQueue(size, overflow)
+		publishStats.Do(func() {
+			expvar.Publish("gocore.log.async", expvar.Func(func() interface{} { return GetAsyncStats() }))
+		})
+	}
+
+	outputs.mu.Lock()
+	previous := outputs.async
+	outputs.async = q
+	outputs.mu.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+}
+
+// GetAsyncStats returns the counters of the async buffer, they are reset by
+// SetAsync
+func GetAsyncStats() AsyncStats {
+	outputs.mu.RLock()
+	q := outputs.async
+	outputs.mu.RUnlock()
+
+	if q == nil {
+		return AsyncStats{Levels: map[string]uint64{}}
+	}
+	return q.stats()
+}
+
+// Flush waits until the buffered entries are written
+func Flush() {
+	outputs.mu.RLock()
+	q := outputs.async
+	outputs.mu.RUnlock()
+
+	if q != nil {
+		q.flush()
+	}
+}
+
+// queue is a bounded ring buffer of entries written by a single goroutine
+type queue struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+
+	entries  []*log.Entry
+	head, n  int
+	busy     bool
+	closed   bool
+	overflow Overflow
+	dropped  map[log.Level]uint64
+	done     chan struct{}
+}
+
+func newQueue(size int, overflow Overflow) *queue {
+	q := &queue{
+		entries:  make([]*log.Entry, size),
+		overflow: overflow,
+		dropped:  make(map[log.Level]uint64),
+		done:     make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	q.idle = sync.NewCond(&q.mu)
+
+	go q.run()
+	return q
+}
+
+// push buffers a copy of e, as logrus keeps using e once hooks return, or
+// drops an entry when full. It returns false once the queue is closed.
+func (q *queue) push(e *log.Entry) bool {
+	entry := *e
+	entry.Buffer = nil
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.n == len(q.entries) && !q.closed {
+		switch q.overflow {
+		case DropOldest:
+			q.drop(0)
+		case DropLowest:
+			i := q.lowest()
+			if q.at(i).Level < entry.Level {
+				q.dropped[entry.Level]++
+				return true
+			}
+			q.drop(i)
+		default:
+			q.notFull.Wait()
+		}
+	}
+	if q.closed {
+		return false
+	}
+
+	q.entries[(q.head+q.n)%len(q.entries)] = &entry
+	q.n++
+	q.notEmpty.Signal()
+	return true
+}
+
+func (q *queue) at(i int) *log.Entry {
+	return q.entries[(q.head+i)%len(q.entries)]
+}
+
+// lowest returns the index of the oldest of the least severe entries
+func (q *queue) lowest() int {
+	lowest := 0
+	for i := 1; i < q.n; i++ {
+		if q.at(i).Level > q.at(lowest).Level {
+			lowest = i
+		}
+	}
+	return lowest
+}
+
+// drop removes the entry at index i, shifting the older ones
+func (q *queue) drop(i int) {
+	q.dropped[q.at(i).Level]++
+	for ; i > 0; i-- {
+		q.entries[(q.head+i)%len(q.entries)] = q.at(i - 1)
+	}
+	q.entries[q.head] = nil
+	q.head = (q.head + 1) % len(q.entries)
+	q.n--
+}
+
+func (q *queue) run() {
+	defer close(q.done)
+
+	q.mu.Lock()
+	for {
+		for q.n == 0 && !q.closed {
+			q.busy = false
+			q.idle.Broadcast()
+			q.notEmpty.Wait()
+		}
+		if q.n == 0 {
+			q.busy = false
+			q.idle.Broadcast()
+			q.mu.Unlock()
+			return
+		}
+
+		e := q.entries[q.head]
+		q.entries[q.head] = nil
+		q.head = (q.head + 1) % len(q.entries)
+		q.n--
+		q.busy = true
+		q.notFull.Signal()
+		q.mu.Unlock()
+
+		outputs.write(e)
+
+		q.mu.Lock()
+	}
+}
+
+func (q *queue) flush() {
+	q.mu.Lock()
+	for q.n > 0 || q.busy {
+		q.idle.Wait()
+	}
+	q.mu.Unlock()
+}
+
+// close writes the buffered entries and stops the goroutine
+func (q *queue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.notEmpty.Signal()
+	q.notFull.Broadcast()
+	q.mu.Unlock()
+
+	<-q.done
+}
+
+func (q *queue) stats() AsyncStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := AsyncStats{Size: len(q.entries), Queued: q.n, Levels: make(map[string]uint64, len(q.dropped))}
+	for level, n := range q.dropped {
+		s.Dropped += n
+		s.Levels[level.String()] += n
+	}
+	return s
+}
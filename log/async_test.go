@@ -0,0 +1,108 @@
+package log
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// gatedWriter blocks writes until open is closed
+type gatedWriter struct {
+	open chan struct{}
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.open
+	w.mu.Lock()
+	w.msgs = append(w.msgs, strings.TrimSpace(string(p)))
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func asyncRun(t *testing.T, overflow Overflow, fn func()) ([]string, AsyncStats) {
+	w := &gatedWriter{open: make(chan struct{})}
+	SetOutputs(NewOutput(w, &log.TextFormatter{DisableTimestamp: true, DisableQuote: true}))
+	SetAsync(2, overflow)
+	defer SetAsync(0, Block)
+	defer SetOutput(ioutil.Discard)
+
+	// the first entry is taken by the writer, which blocks on it
+	Topic("t").Infof("first")
+	for GetAsyncStats().Queued != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	fn()
+	stats := GetAsyncStats()
+	close(w.open)
+	Flush()
+
+	var msgs []string
+	for _, m := range w.msgs {
+		msgs = append(msgs, strings.Fields(m[strings.Index(m, "msg=")+4:])[0])
+	}
+	return msgs, stats
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	msgs, stats := asyncRun(t, DropOldest, func() {
+		Topic("t").Errorf("a")
+		Topic("t").Infof("b")
+		Topic("t").Infof("c")
+	})
+
+	if strings.Join(msgs, ",") != "first,b,c" {
+		t.Errorf("oldest entry should be dropped, got %v", msgs)
+	}
+	if stats.Dropped != 1 || stats.Levels["error"] != 1 || stats.Queued != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestAsyncDropLowest(t *testing.T) {
+	msgs, stats := asyncRun(t, DropLowest, func() {
+		Topic("t").Infof("a")
+		Topic("t").Errorf("b")
+		Topic("t").Warnf("c")
+		Topic("t").Infof("d")
+	})
+
+	if strings.Join(msgs, ",") != "first,b,c" {
+		t.Errorf("least severe entries should be dropped, got %v", msgs)
+	}
+	if stats.Dropped != 2 || stats.Levels["info"] != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestAsyncBlock(t *testing.T) {
+	done := make(chan struct{})
+	msgs, _ := asyncRun(t, Block, func() {
+		go func() {
+			for _, m := range []string{"a", "b", "c"} {
+				Topic("t").Infof(m)
+			}
+			close(done)
+		}()
+		for GetAsyncStats().Queued != 2 {
+			time.Sleep(time.Millisecond)
+		}
+		select {
+		case <-done:
+			t.Error("logging should block while the buffer is full")
+		default:
+		}
+	})
+	<-done
+	Flush()
+
+	if len(msgs) < 3 || msgs[0] != "first" {
+		t.Errorf("no entry should be dropped, got %v", msgs)
+	}
+}
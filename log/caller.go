@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxFrames bounds the frames walked to find the caller and build stacks
+const maxFrames = 64
+
+// Caller sets how the caller of an entry is found and reported. Frames of
+// go-core packages are always skipped, then the frames of Packages, e.g. an
+// application logging wrapper, and then Skip more frames.
+//
+// The caller is written as "path" with the file and line and, with Function,
+// "func" with the function name. Entries at StackLevel and above get a
+// compact "stack" trace, starting at the caller.
+type Caller struct {
+	Disabled   bool
+	Skip       int
+	Packages   []string
+	Function   bool
+	NoStack    bool
+	StackLevel log.Level
+}
+
+var caller = struct {
+	sync.RWMutex
+	Caller
+}{Caller: Caller{StackLevel: log.ErrorLevel}}
+
+// modulePath is the import path of go-core as compiled, e.g.
+// github.com/maps90/go-core or a vendored copy, its frames are never the
+// caller
+var modulePath = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	return name[:strings.LastIndex(name, "/log.")]
+}()
+
+// SetCaller replaces the caller settings, by default the caller is reported
+// without function and error entries get a stack trace
+func SetCaller(c Caller) {
+	caller.Lock()
+	caller.Caller = c
+	caller.Unlock()
+}
+
+// callerFields returns the caller and stack fields of an entry at level
+func callerFields(level log.Level) log.Fields {
+	caller.RLock()
+	c := caller.Caller
+	caller.RUnlock()
+
+	withStack := !c.NoStack && level <= c.StackLevel
+	if c.Disabled && !withStack {
+		return nil
+	}
+
+	pcs := make([]uintptr, maxFrames)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	var stack []runtime.Frame
+	skip := c.Skip
+	for {
+		f, more := frames.Next()
+		switch {
+		case len(stack) == 0 && internal(f):
+		case len(stack) == 0 && wrapper(c.Packages, f.Function):
+		case len(stack) == 0 && skip > 0:
+			skip--
+		case f.Function == "runtime.goexit":
+		default:
+			stack = append(stack, f)
+		}
+		if !more || (!withStack && len(stack) > 0) {
+			break
+		}
+	}
+	if len(stack) == 0 {
+		return nil
+	}
+
+	fields := log.Fields{}
+	if !c.Disabled {
+		fields["path"] = fmt.Sprintf("%s:%d", stack[0].File, stack[0].Line)
+		if c.Function {
+			fields["func"] = shortFunction(stack[0].Function)
+		}
+	}
+	if withStack {
+		lines := make([]string, len(stack))
+		for i, f := range stack {
+			lines[i] = fmt.Sprintf("%s %s:%d", shortFunction(f.Function), shortFile(f.File), f.Line)
+		}
+		fields["stack"] = strings.Join(lines, "\n")
+	}
+	return fields
+}
+
+// internal reports whether f belongs to a go-core package, tests excluded
+func internal(f runtime.Frame) bool {
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	return strings.HasPrefix(f.Function, modulePath+"/") || strings.HasPrefix(f.Function, modulePath+".")
+}
+
+func wrapper(packages []string, function string) bool {
+	for _, pkg := range packages {
+		if strings.HasPrefix(function, pkg+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// shortFunction strips the import path, e.g. datasource.(*Sqlite).Init
+func shortFunction(function string) string {
+	return function[strings.LastIndexByte(function, '/')+1:]
+}
+
+// shortFile keeps the directory and the name of file, e.g. datasource/sqlite.go
+func shortFile(file string) string {
+	dir, name := filepath.Split(file)
+	return filepath.Join(filepath.Base(dir), name)
+}
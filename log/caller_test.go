@@ -0,0 +1,82 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logWrapper(msg string) {
+	Topic("t").Errorf(msg)
+}
+
+func line() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestCaller(t *testing.T) {
+	defer SetCaller(Caller{StackLevel: ErrorLevelLog})
+
+	var want int
+	entries := capture(t, func() {
+		New(InfoLevelLog, "t", "legacy")
+		want = line() - 1
+
+		SetCaller(Caller{Skip: 1, Function: true, StackLevel: ErrorLevelLog})
+		logWrapper("wrapped")
+	})
+
+	if path := entries[0]["path"].(string); !strings.HasSuffix(path, "caller_test.go:"+strconv.Itoa(want)) {
+		t.Errorf("path should be the caller of New, got %s", path)
+	}
+	if entries[0]["func"] != nil || entries[0]["stack"] != nil {
+		t.Errorf("info entries should have no function nor stack, got %v", entries[0])
+	}
+
+	if entries[1]["func"] != "log.TestCaller.func1" {
+		t.Errorf("skip should resolve the caller of the wrapper, got %v", entries[1]["func"])
+	}
+	stack := entries[1]["stack"].(string)
+	if !strings.HasPrefix(stack, "log.TestCaller.func1 log/caller_test.go:") || strings.Contains(stack, "log.emit") {
+		t.Errorf("stack should start at the caller without internal frames, got\n%s", stack)
+	}
+}
+
+func TestCallerShallow(t *testing.T) {
+	done := make(chan struct{})
+	entries := capture(t, func() {
+		go func() {
+			defer close(done)
+			SetCaller(Caller{Packages: []string{"github.com/maps90/go-core/log"}})
+			Errorf("shallow")
+		}()
+		<-done
+		time.Sleep(time.Millisecond)
+	})
+	SetCaller(Caller{StackLevel: ErrorLevelLog})
+
+	if len(entries) != 1 || entries[0]["path"] != nil {
+		t.Errorf("entries without caller should still be written, got %v", entries)
+	}
+}
+
+func TestCallerInternal(t *testing.T) {
+	if modulePath != "github.com/maps90/go-core" {
+		t.Fatalf("unexpected module path %s", modulePath)
+	}
+	for f, want := range map[runtime.Frame]bool{
+		{Function: "github.com/maps90/go-core/log.emit", File: "/src/go-core/log/sample.go"}:                         true,
+		{Function: "github.com/maps90/go-core/datasource.(*Sqlite).Init", File: "/src/go-core/datasource/sqlite.go"}: true,
+		{Function: "github.com/maps90/go-core.(*Route).Run", File: "/src/go-core/router.go"}:                         true,
+		{Function: "github.com/maps90/go-core/log.TestCaller", File: "/src/go-core/log/caller_test.go"}:              false,
+		{Function: "github.com/maps90/go-core-extra/billing.Charge", File: "/src/go-core-extra/billing/billing.go"}:  false,
+		{Function: "main.main", File: "/src/app/main.go"}:                                                            false,
+	} {
+		if internal(f) != want {
+			t.Errorf("internal(%s) should be %v", f.Function, want)
+		}
+	}
+}
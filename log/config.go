@@ -0,0 +1,395 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maps90/go-core/report"
+	log "github.com/sirupsen/logrus"
+	config "github.com/spf13/viper"
+)
+
+// Config describes the level and outputs of the logger, e.g.
+//
+//	log:
+//	  level: info
+//	  topics:
+//	    sql: debug
+//	  auto_revert: 15m
+//	  sampling:
+//	    first: 10
+//	    thereafter: 100
+//	    interval: 1s
+//	    topics:
+//	      datasource: {first: 1, interval: 10s}
+//	  async:
+//	    size: 4096
+//	    overflow: drop_lowest
+//	  caller:
+//	    function: true
+//	    packages: [github.com/acme/app/logging]
+//	    stack_level: error
+//	  sentry:
+//	    dsn: https://public@sentry.example.com/1
+//	    environment: production
+//	    release: 1.4.2
+//	  outputs:
+//	    - type: stderr
+//	      format: text
+//	      color: true
+//	    - type: file
+//	      format: ecs
+//	      path: /var/log/app/app.log
+//	      max_size: 100
+//	      interval: 24h
+//	      max_backups: 7
+//	      max_age: 168h
+//	      compress: true
+//	    - type: syslog
+//	      network: udp
+//	      address: syslog.example.com:514
+//	      facility: local0
+//	    - type: tcp
+//	      address: vector.example.com:9000
+//	      buffer: 10000
+type Config struct {
+	Level  string            `mapstructure:"level" json:"level"`
+	Topics map[string]string `mapstructure:"topics" json:"topics"`
+	// AutoRevert is passed to SetAutoRevert
+	AutoRevert time.Duration  `mapstructure:"auto_revert" json:"auto_revert"`
+	Sampling   SamplingConfig `mapstructure:"sampling" json:"sampling"`
+	Async      AsyncConfig    `mapstructure:"async" json:"async"`
+	Caller     CallerConfig   `mapstructure:"caller" json:"caller"`
+	Sentry     SentryConfig   `mapstructure:"sentry" json:"sentry"`
+	Outputs    []OutputConfig `mapstructure:"outputs" json:"outputs"`
+}
+
+// CallerConfig is passed to SetCaller, StackLevel is a level name, error by
+// default, or none
+type CallerConfig struct {
+	Disabled   bool     `mapstructure:"disabled" json:"disabled"`
+	Skip       int      `mapstructure:"skip" json:"skip"`
+	Packages   []string `mapstructure:"packages" json:"packages"`
+	Function   bool     `mapstructure:"function" json:"function"`
+	StackLevel string   `mapstructure:"stack_level" json:"stack_level"`
+}
+
+func (c CallerConfig) caller() (Caller, error) {
+	cl := Caller{
+		Disabled:   c.Disabled,
+		Skip:       c.Skip,
+		Packages:   c.Packages,
+		Function:   c.Function,
+		StackLevel: log.ErrorLevel,
+	}
+
+	switch c.StackLevel {
+	case "":
+	case "none":
+		cl.NoStack = true
+	default:
+		level, err := log.ParseLevel(c.StackLevel)
+		if err != nil {
+			return cl, fmt.Errorf("log config: caller.stack_level %v", err)
+		}
+		cl.StackLevel = level
+	}
+	return cl, nil
+}
+
+// SentryConfig sets the Sentry reporter of error entries when DSN is set,
+// see report.Set
+type SentryConfig struct {
+	DSN         string            `mapstructure:"dsn" json:"dsn"`
+	Environment string            `mapstructure:"environment" json:"environment"`
+	Release     string            `mapstructure:"release" json:"release"`
+	Tags        map[string]string `mapstructure:"tags" json:"tags"`
+}
+
+func (c SentryConfig) reporter() (report.ErrorReporter, error) {
+	if c.DSN == "" {
+		return nil, nil
+	}
+
+	s, err := report.NewSentry(c.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("log config: sentry %v", err)
+	}
+	s.SetEnvironment(c.Environment)
+	s.SetRelease(c.Release)
+	s.SetTags(c.Tags)
+	return s, nil
+}
+
+// AsyncConfig is passed to SetAsync, Overflow is block, drop_oldest or
+// drop_lowest
+type AsyncConfig struct {
+	Size     int    `mapstructure:"size" json:"size"`
+	Overflow string `mapstructure:"overflow" json:"overflow"`
+}
+
+// SamplingConfig is the default sampling and the sampling of topics
+type SamplingConfig struct {
+	Sampling `mapstructure:",squash"`
+	Topics   map[string]Sampling `mapstructure:"topics" json:"topics"`
+}
+
+// OutputConfig describes one output, Type is "stderr", "stdout", "file",
+// "syslog", "journald", or "tcp" and "udp" for a Shipper, and Format one of
+// the formats of NewFormatter, json by default
+type OutputConfig struct {
+	Type   string   `mapstructure:"type" json:"type"`
+	Path   string   `mapstructure:"path" json:"path"`
+	Format string   `mapstructure:"format" json:"format"`
+	Fields FieldMap `mapstructure:"fields" json:"fields"`
+	// Color enables colors of the text format
+	Color bool `mapstructure:"color" json:"color"`
+
+	// MaxSize is in megabytes
+	MaxSize    int64         `mapstructure:"max_size" json:"max_size"`
+	Interval   time.Duration `mapstructure:"interval" json:"interval"`
+	MaxBackups int           `mapstructure:"max_backups" json:"max_backups"`
+	MaxAge     time.Duration `mapstructure:"max_age" json:"max_age"`
+	Compress   bool          `mapstructure:"compress" json:"compress"`
+
+	// Network is the network of syslog, Address the address of syslog and
+	// the shippers and Path the socket of journald
+	Network string `mapstructure:"network" json:"network"`
+	Address string `mapstructure:"address" json:"address"`
+	// Facility is a name of ParseFacility, AppName the syslog APP-NAME or
+	// the journald SYSLOG_IDENTIFIER
+	Facility string `mapstructure:"facility" json:"facility"`
+	AppName  string `mapstructure:"app_name" json:"app_name"`
+	// Buffer is the number of entries buffered while disconnected
+	Buffer  int           `mapstructure:"buffer" json:"buffer"`
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+}
+
+var (
+	outputMu sync.Mutex
+	files    []*RotatingFile
+	conns    []io.Closer
+)
+
+// LoadConfig reads the config section at key
+func LoadConfig(key string) (*Config, error) {
+	if !config.IsSet(key) {
+		return nil, fmt.Errorf("log config: %s is not set", key)
+	}
+
+	c := new(Config)
+	if err := config.UnmarshalKey(key, c); err != nil {
+		return nil, fmt.Errorf("log config: %v", err)
+	}
+
+	return c, nil
+}
+
+// Configure applies c to the logger, files opened by a previous call are
+// closed once the new outputs are in place
+func Configure(c *Config) error {
+	level, topics, err := c.levels()
+	if err != nil {
+		return err
+	}
+	overflow, err := ParseOverflow(c.Async.Overflow)
+	if err != nil {
+		return fmt.Errorf("log config: %v", err)
+	}
+	cl, err := c.Caller.caller()
+	if err != nil {
+		return err
+	}
+	reporter, err := c.Sentry.reporter()
+	if err != nil {
+		return err
+	}
+
+	var outs []*Output
+	var opened []*RotatingFile
+	var dialed []io.Closer
+	// network outputs run a writer, close the outputs of a failed config
+	installed := false
+	defer func() {
+		if installed {
+			return
+		}
+		for _, f := range opened {
+			f.Close()
+		}
+		for _, c := range dialed {
+			c.Close()
+		}
+	}()
+	for i, o := range c.Outputs {
+		formatter, err := NewFormatter(o.Format, o.Fields)
+		if err != nil {
+			return fmt.Errorf("log config: outputs[%d] %v", i, err)
+		}
+		if text, ok := formatter.(*TextFormatter); ok {
+			text.Color = o.Color
+		}
+
+		var w io.Writer
+		switch o.Type {
+		case "", "stderr":
+			w = os.Stderr
+		case "stdout":
+			w = os.Stdout
+		case "file":
+			if o.Path == "" {
+				return fmt.Errorf("log config: outputs[%d] path is required", i)
+			}
+			f := NewRotatingFile(o.Path)
+			f.SetMaxSize(o.MaxSize << 20)
+			f.SetInterval(o.Interval)
+			f.SetMaxBackups(o.MaxBackups)
+			f.SetMaxAge(o.MaxAge)
+			f.SetCompress(o.Compress)
+			w = f
+			opened = append(opened, f)
+		case "syslog":
+			facility, err := ParseFacility(o.Facility)
+			if err != nil {
+				return fmt.Errorf("log config: outputs[%d] %v", i, err)
+			}
+			s := NewSyslog(o.Network, o.Address)
+			s.SetFacility(facility)
+			if o.AppName != "" {
+				s.SetAppName(o.AppName)
+			}
+			o.connection(s)
+			w = s
+			dialed = append(dialed, s)
+		case "journald":
+			j := NewJournald(o.Path)
+			if o.AppName != "" {
+				j.SetIdentifier(o.AppName)
+			}
+			w = j
+			dialed = append(dialed, j)
+		case "tcp", "udp":
+			if o.Address == "" {
+				return fmt.Errorf("log config: outputs[%d] address is required", i)
+			}
+			s := NewShipper(o.Type, o.Address)
+			o.connection(s)
+			w = s
+			dialed = append(dialed, s)
+		default:
+			return fmt.Errorf("log config: outputs[%d] unknown type %q", i, o.Type)
+		}
+		outs = append(outs, NewOutput(w, formatter))
+	}
+	if len(outs) == 0 {
+		outs = append(outs, NewOutput(os.Stderr, &log.JSONFormatter{}))
+	}
+
+	installed = true
+	outputMu.Lock()
+	previous, previousConns := files, conns
+	files, conns = opened, dialed
+	SetLevels(level, topics)
+	SetAutoRevert(c.AutoRevert)
+	SetSamplings(c.Sampling.Sampling, c.Sampling.Topics)
+	SetCaller(cl)
+	SetOutputs(outs...)
+	outputMu.Unlock()
+	SetAsync(c.Async.Size, overflow)
+	if reporter != nil {
+		report.Set(reporter)
+	}
+
+	if len(opened) > 0 {
+		ReopenOnSignal(opened...)
+	}
+	stopReopen(previous)
+	for _, f := range previous {
+		f.Close()
+	}
+	for _, c := range previousConns {
+		c.Close()
+	}
+
+	return nil
+}
+
+// connection sets the buffer and timeout of a network output
+func (o OutputConfig) connection(c interface {
+	SetBuffer(int)
+	SetTimeout(time.Duration)
+}) {
+	if o.Buffer > 0 {
+		c.SetBuffer(o.Buffer)
+	}
+	if o.Timeout > 0 {
+		c.SetTimeout(o.Timeout)
+	}
+}
+
+// ApplyLevels applies the levels of c only, e.g. when the config is
+// reloaded, see Configuration.WatchLog
+func ApplyLevels(c *Config) error {
+	level, topics, err := c.levels()
+	if err != nil {
+		return err
+	}
+
+	SetLevels(level, topics)
+	SetAutoRevert(c.AutoRevert)
+	return nil
+}
+
+func (c *Config) levels() (log.Level, map[string]log.Level, error) {
+	level := log.InfoLevel
+	if c.Level != "" {
+		l, err := log.ParseLevel(c.Level)
+		if err != nil {
+			return level, nil, fmt.Errorf("log config: %v", err)
+		}
+		level = l
+	}
+
+	topics := make(map[string]log.Level, len(c.Topics))
+	for topic, name := range c.Topics {
+		l, err := log.ParseLevel(name)
+		if err != nil {
+			return level, nil, fmt.Errorf("log config: topics.%s %v", topic, err)
+		}
+		topics[topic] = l
+	}
+
+	return level, topics, nil
+}
+
+// Close writes the pending sampled and buffered entries, waits for the queued
+// reports and closes the files and connections opened by Configure, entries
+// logged afterwards go to stderr
+func Close() error {
+	flushSampler()
+	Flush()
+	report.Flush(reportTimeout)
+
+	outputMu.Lock()
+	previous, previousConns := files, conns
+	files, conns = nil, nil
+	SetOutput(os.Stderr)
+	outputMu.Unlock()
+
+	stopReopen(previous)
+	var err error
+	for _, f := range previous {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, c := range previousConns {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,252 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultBuffer is the default number of messages buffered by the network
+	// outputs while they are disconnected
+	DefaultBuffer = 1024
+	// DefaultTimeout is the default dial and write timeout of network outputs
+	DefaultTimeout = 5 * time.Second
+
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+var errClosed = errors.New("log: output is closed")
+
+// conn sends messages over a connection owned by a background writer. send
+// only buffers the messages, the writer dials on the first one, writes them
+// and reconnects with a growing backoff when the connection fails, so an
+// unreachable collector does not block logging. The oldest messages are
+// dropped once the buffer is full, messages larger than a datagram are
+// dropped too.
+type conn struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	c       net.Conn
+	pending [][]byte
+	size    int
+	timeout time.Duration
+	dropped uint64
+	closed  bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	err  error
+}
+
+func newConn(network, addr string) *conn {
+	c := &conn{
+		network: network,
+		addr:    addr,
+		size:    DefaultBuffer,
+		timeout: DefaultTimeout,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *conn) setBuffer(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+	c.size = n
+	for len(c.pending) > n {
+		c.drop()
+	}
+}
+
+func (c *conn) setTimeout(d time.Duration) {
+	c.mu.Lock()
+	c.timeout = d
+	c.mu.Unlock()
+}
+
+// send buffers msg, which must not be modified afterwards, for the writer
+func (c *conn) send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errClosed
+	}
+	if len(c.pending) >= c.size {
+		c.drop()
+	}
+	c.pending = append(c.pending, msg)
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// drop drops the oldest buffered message, must be called with mu held
+func (c *conn) drop() {
+	c.pending[0] = nil
+	c.pending = c.pending[1:]
+	c.dropped++
+}
+
+// run is the writer, it writes the buffered messages until close and retries
+// failures after the backoff, without waiting for the next message. The
+// first failure of an outage goes to stderr as logging it would loop.
+func (c *conn) run() {
+	defer close(c.done)
+
+	var backoff time.Duration
+	for {
+		select {
+		case <-c.wake:
+		case <-c.stop:
+			c.finish()
+			return
+		}
+
+		for {
+			err := c.flush()
+			if err == nil {
+				backoff = 0
+				break
+			}
+			if backoff == 0 {
+				fmt.Fprintln(os.Stderr, err)
+			}
+
+			backoff *= 2
+			if backoff < minBackoff {
+				backoff = minBackoff
+			} else if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			t := time.NewTimer(backoff)
+			select {
+			case <-t.C:
+			case <-c.stop:
+				t.Stop()
+				c.finish()
+				return
+			}
+		}
+	}
+}
+
+// flush writes the buffered messages, dialing when disconnected, the messages
+// not written are buffered again
+func (c *conn) flush() error {
+	c.mu.Lock()
+	nc, timeout, batch := c.c, c.timeout, c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if nc == nil {
+		var err error
+		if nc, err = net.DialTimeout(c.network, c.addr, timeout); err != nil {
+			c.requeue(batch)
+			return fmt.Errorf("log: connect %s %s: %v", c.network, c.addr, err)
+		}
+		c.mu.Lock()
+		c.c = nc
+		c.mu.Unlock()
+	}
+
+	for i, msg := range batch {
+		if timeout > 0 {
+			nc.SetWriteDeadline(time.Now().Add(timeout))
+		}
+		_, err := nc.Write(msg)
+		if errors.Is(err, syscall.EMSGSIZE) {
+			// a message larger than a datagram is never sent, retrying it
+			// would hold back the next ones
+			fmt.Fprintf(os.Stderr, "log: drop message of %d bytes to %s %s: %v\n", len(msg), c.network, c.addr, err)
+			c.mu.Lock()
+			c.dropped++
+			c.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			// the message is sent again once reconnected
+			nc.Close()
+			c.mu.Lock()
+			c.c = nil
+			c.mu.Unlock()
+			c.requeue(batch[i:])
+			return fmt.Errorf("log: write %s %s: %v", c.network, c.addr, err)
+		}
+	}
+	return nil
+}
+
+// requeue puts back messages taken by flush before the ones sent meanwhile
+func (c *conn) requeue(msgs [][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = append(append([][]byte(nil), msgs...), c.pending...)
+	for len(c.pending) > c.size {
+		c.drop()
+	}
+}
+
+// finish tries once more to write the buffered messages, without waiting for
+// the backoff, and closes the connection, the messages still buffered are
+// dropped
+func (c *conn) finish() {
+	err := c.flush()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropped += uint64(len(c.pending))
+	c.pending = nil
+	if c.c != nil {
+		if e := c.c.Close(); err == nil {
+			err = e
+		}
+		c.c = nil
+	}
+	c.err = err
+}
+
+func (c *conn) droppedCount() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// close stops the writer once it has tried to write the buffered messages and
+// returns the error of that last attempt
+func (c *conn) close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.stop)
+	<-c.done
+	return c.err
+}
@@ -0,0 +1,28 @@
+package log
+
+import "github.com/labstack/echo"
+
+// FromEcho returns the logger of the request, see FromContext, with the
+// matched route and the user set after the logger middleware ran
+func FromEcho(c echo.Context) *Logger {
+	ctx := c.Request().Context()
+	l := FromContext(ctx)
+
+	fields := Fields{}
+	if _, ok := l.fields["route"]; !ok && c.Path() != "" {
+		fields["route"] = c.Path()
+	}
+	if _, ok := l.fields["request_id"]; !ok {
+		if id := c.Request().Header.Get("X-Request-Id"); id != "" {
+			fields["request_id"] = id
+		}
+	}
+	if user := User(ctx); user != "" {
+		fields["user"] = user
+	}
+	if len(fields) == 0 {
+		return l
+	}
+
+	return l.With(fields)
+}
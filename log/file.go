@@ -0,0 +1,398 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// now is replaced in tests
+var now = time.Now
+
+// RotatingFile is an io.WriteCloser appending to a file which is rotated once
+// it reaches a size or at a fixed interval, e.g.
+//
+//	f := log.NewRotatingFile("/var/log/app/app.log")
+//	f.SetMaxSize(100 << 20)
+//	f.SetMaxBackups(7)
+//	f.SetCompress(true)
+//	log.SetOutput(f)
+//
+// Rotated files are renamed to app-<time>.log in the same directory and
+// compressed and pruned in the background. It is safe for concurrent use.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	next time.Time
+
+	millMu sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// NewRotatingFile returns a RotatingFile writing to path, the file is opened
+// on the first write
+func NewRotatingFile(path string) *RotatingFile {
+	return &RotatingFile{path: path}
+}
+
+// SetMaxSize rotates the file before a write would grow it past size bytes,
+// zero disables size based rotation
+func (f *RotatingFile) SetMaxSize(size int64) {
+	f.mu.Lock()
+	f.maxSize = size
+	f.mu.Unlock()
+}
+
+// SetInterval rotates the file every interval, aligned to UTC, e.g. 24h
+// rotates at midnight UTC. Zero disables time based rotation.
+func (f *RotatingFile) SetInterval(interval time.Duration) {
+	f.mu.Lock()
+	f.interval = interval
+	f.next = f.nextRotation()
+	f.mu.Unlock()
+}
+
+// SetMaxBackups keeps at most n rotated files, zero keeps all of them
+func (f *RotatingFile) SetMaxBackups(n int) {
+	f.mu.Lock()
+	f.maxBackups = n
+	f.mu.Unlock()
+}
+
+// SetMaxAge removes rotated files older than age, zero keeps all of them
+func (f *RotatingFile) SetMaxAge(age time.Duration) {
+	f.mu.Lock()
+	f.maxAge = age
+	f.mu.Unlock()
+}
+
+// SetCompress gzips rotated files
+func (f *RotatingFile) SetCompress(compress bool) {
+	f.mu.Lock()
+	f.compress = compress
+	f.mu.Unlock()
+}
+
+// Path returns the path of the current file
+func (f *RotatingFile) Path() string {
+	return f.path
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the current file and opens a new one
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	return f.rotate()
+}
+
+// Reopen closes and reopens the file at its path, for use after an external
+// tool such as logrotate moved it
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// Close closes the file and waits for pending compression and pruning
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.next = f.nextRotation()
+	return nil
+}
+
+func (f *RotatingFile) nextRotation() time.Time {
+	if f.interval <= 0 {
+		return time.Time{}
+	}
+	return now().Truncate(f.interval).Add(f.interval)
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.maxSize > 0 && f.size > 0 && f.size+n > f.maxSize {
+		return true
+	}
+	return f.interval > 0 && !now().Before(f.next)
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	t := now()
+	backup := f.backupName(t)
+	if err := os.Rename(f.path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if f.maxAge > 0 {
+		cutoff = t.Add(-f.maxAge)
+	}
+	f.wg.Add(1)
+	go f.mill(backup, f.compress, f.maxBackups, cutoff)
+	return nil
+}
+
+// backupName returns a free name for a file rotated at t
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := f.nameParts()
+	stamp := t.UTC().Format(backupTimeFormat)
+
+	name := filepath.Join(dir, prefix+stamp+ext)
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = filepath.Join(dir, fmt.Sprintf("%s%s-%d%s", prefix, stamp, i, ext))
+	}
+	return name
+}
+
+func (f *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.path)
+	base := filepath.Base(f.path)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+	return
+}
+
+// mill compresses backup and removes the files past maxBackups or rotated
+// before cutoff
+func (f *RotatingFile) mill(backup string, compress bool, maxBackups int, cutoff time.Time) {
+	defer f.wg.Done()
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	if compress && exists(backup) {
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "log: compress %s: %v\n", backup, err)
+		}
+	}
+
+	if maxBackups <= 0 && cutoff.IsZero() {
+		return
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log: list backups of %s: %v\n", f.path, err)
+		return
+	}
+	for i, b := range backups {
+		if (maxBackups > 0 && i >= maxBackups) || b.time.Before(cutoff) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+type backup struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+// backups returns the rotated files of f, newest first
+func (f *RotatingFile) backups() ([]backup, error) {
+	dir, prefix, ext := f.nameParts()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		stamp = strings.TrimPrefix(stamp, prefix)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, stamp[:len(backupTimeFormat)])
+		if err != nil {
+			continue
+		}
+		seq := 0
+		if rest := stamp[len(backupTimeFormat):]; rest != "" {
+			if seq, err = strconv.Atoi(strings.TrimPrefix(rest, "-")); err != nil || seq <= 0 {
+				continue
+			}
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), time: t, seq: seq})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		if backups[i].time.Equal(backups[j].time) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+var (
+	reopenMu    sync.Mutex
+	reopenFiles []*RotatingFile
+	reopenOnce  sync.Once
+)
+
+// ReopenOnSignal reopens files when the process receives SIGHUP, which is
+// what logrotate sends after moving them
+func ReopenOnSignal(files ...*RotatingFile) {
+	reopenMu.Lock()
+	reopenFiles = append(reopenFiles, files...)
+	reopenMu.Unlock()
+
+	reopenOnce.Do(func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+		go func() {
+			for range sig {
+				reopenMu.Lock()
+				for _, f := range reopenFiles {
+					if err := f.Reopen(); err != nil {
+						fmt.Fprintf(os.Stderr, "log: reopen %s: %v\n", f.path, err)
+					}
+				}
+				reopenMu.Unlock()
+			}
+		}()
+	})
+}
+
+func stopReopen(files []*RotatingFile) {
+	reopenMu.Lock()
+	defer reopenMu.Unlock()
+
+	kept := reopenFiles[:0]
+	for _, f := range reopenFiles {
+		closed := false
+		for _, c := range files {
+			closed = closed || f == c
+		}
+		if !closed {
+			kept = append(kept, f)
+		}
+	}
+	reopenFiles = kept
+}
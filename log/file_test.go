@@ -0,0 +1,161 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setNow(t time.Time) func() {
+	now = func() time.Time { return t }
+	return func() { now = time.Now }
+}
+
+func logFiles(t *testing.T, dir string) (logs, gz []string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), ".gz") {
+			gz = append(gz, info.Name())
+		} else {
+			logs = append(logs, info.Name())
+		}
+	}
+	return
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "log")
+	defer os.RemoveAll(dir)
+
+	f := NewRotatingFile(filepath.Join(dir, "app.log"))
+	f.SetMaxSize(10)
+	f.SetMaxBackups(2)
+	f.SetCompress(true)
+
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	logs, gz := logFiles(t, dir)
+	if len(logs) != 1 || logs[0] != "app.log" {
+		t.Errorf("should keep only the current file uncompressed, got %v", logs)
+	}
+	if len(gz) != 2 {
+		t.Fatalf("should keep 2 compressed backups, got %v", gz)
+	}
+
+	r, err := os.Open(filepath.Join(dir, gz[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != "0123456789" {
+		t.Errorf("unexpected backup content %q", b)
+	}
+}
+
+func TestRotateByTime(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "log")
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+	defer setNow(start)()
+
+	f := NewRotatingFile(filepath.Join(dir, "app.log"))
+	f.SetInterval(24 * time.Hour)
+	f.SetMaxAge(48 * time.Hour)
+	f.Write([]byte("first\n"))
+
+	setNow(start.Add(2 * time.Hour))
+	f.Write([]byte("second\n"))
+	setNow(start.Add(26 * time.Hour))
+	f.Write([]byte("third\n"))
+	setNow(start.Add(51 * time.Hour))
+	f.Write([]byte("fourth\n"))
+	f.Close()
+
+	logs, _ := logFiles(t, dir)
+	if len(logs) != 3 {
+		t.Fatalf("backups older than 48h should be removed, got %v", logs)
+	}
+	if logs[0] != "app-2026-01-03T01-00-00.000.log" {
+		t.Errorf("unexpected backup name %s", logs[0])
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "app.log")); string(b) != "fourth\n" {
+		t.Errorf("unexpected current content %q", b)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "log")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	f := NewRotatingFile(path)
+	defer f.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Write([]byte("line\n"))
+		}()
+	}
+	wg.Wait()
+
+	os.Rename(path, path+".1")
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("after\n"))
+
+	if b, _ := ioutil.ReadFile(path + ".1"); len(b) != 50 {
+		t.Errorf("moved file should have 10 lines, got %q", b)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "after\n" {
+		t.Errorf("reopened file should be new, got %q", b)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "log")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	err := Configure(&Config{
+		Level:   "warning",
+		Outputs: []OutputConfig{{Type: "file", Path: path}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Infof("dropped")
+	Warnf("kept")
+	Close()
+	SetLevel(InfoLevelLog)
+
+	b, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(b), "dropped") || !strings.Contains(string(b), "kept") {
+		t.Errorf("unexpected file content %q", b)
+	}
+
+	if err := Configure(&Config{Outputs: []OutputConfig{{Type: "kafka"}}}); err == nil {
+		t.Error("unknown output type should fail")
+	}
+}
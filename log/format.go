@@ -0,0 +1,314 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/gommon/color"
+	log "github.com/sirupsen/logrus"
+)
+
+// Formats accepted by NewFormatter
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+	FormatECS    = "ecs"
+	FormatGELF   = "gelf"
+)
+
+// ecsVersion is the Elastic Common Schema version written by ECSFormatter
+const ecsVersion = "1.12.0"
+
+// FieldMap renames the time, level and message keys of the json and logfmt
+// formats and sets their time layout, empty values keep the defaults. The
+// text format only uses TimeFormat.
+type FieldMap struct {
+	Time       string `mapstructure:"time" json:"time"`
+	Level      string `mapstructure:"level" json:"level"`
+	Message    string `mapstructure:"message" json:"message"`
+	TimeFormat string `mapstructure:"time_format" json:"time_format"`
+}
+
+func (m FieldMap) withDefaults() FieldMap {
+	if m.Time == "" {
+		m.Time = log.FieldKeyTime
+	}
+	if m.Level == "" {
+		m.Level = log.FieldKeyLevel
+	}
+	if m.Message == "" {
+		m.Message = log.FieldKeyMsg
+	}
+	if m.TimeFormat == "" {
+		m.TimeFormat = time.RFC3339
+	}
+	return m
+}
+
+// NewFormatter returns the formatter for format, one of the Format constants,
+// the GELF and ECS schemas have fixed keys and ignore m
+func NewFormatter(format string, m FieldMap) (log.Formatter, error) {
+	switch format {
+	case "", FormatJSON:
+		m = m.withDefaults()
+		return &log.JSONFormatter{
+			TimestampFormat: m.TimeFormat,
+			FieldMap: log.FieldMap{
+				log.FieldKeyTime:  m.Time,
+				log.FieldKeyLevel: m.Level,
+				log.FieldKeyMsg:   m.Message,
+			},
+		}, nil
+	case FormatText:
+		return &TextFormatter{FieldMap: m}, nil
+	case FormatLogfmt:
+		return &LogfmtFormatter{FieldMap: m}, nil
+	case FormatECS:
+		return &ECSFormatter{}, nil
+	case FormatGELF:
+		return &GELFFormatter{}, nil
+	}
+
+	return nil, fmt.Errorf("log: unknown format %q", format)
+}
+
+// TextFormatter writes human readable lines for local development, e.g.
+//
+//	15:04:05.000 INFO  [billing] charged invoice=7
+//
+// The time layout defaults to 15:04:05.000.
+type TextFormatter struct {
+	FieldMap FieldMap
+	// Color enables ANSI colors
+	Color bool
+}
+
+func (f *TextFormatter) Format(e *log.Entry) ([]byte, error) {
+	layout := f.FieldMap.TimeFormat
+	if layout == "" {
+		layout = "15:04:05.000"
+	}
+	c := plain
+	if f.Color {
+		c = colored
+	}
+
+	var b bytes.Buffer
+	b.WriteString(c.Grey(e.Time.Format(layout)))
+	b.WriteByte(' ')
+	b.WriteString(levelColor(c, e.Level, fmt.Sprintf("%-5.5s", strings.ToUpper(e.Level.String()))))
+
+	fields := e.Data
+	if topic, ok := fields["topic"]; ok {
+		b.WriteString(" " + c.Cyan(fmt.Sprintf("[%v]", topic)))
+	}
+	b.WriteString(" " + e.Message)
+
+	for _, k := range sortedKeys(fields) {
+		if k == "topic" {
+			continue
+		}
+		b.WriteString(" " + c.Dim(k+"=") + logfmtValue(fields[k]))
+	}
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+var plain, colored = newColor(false), newColor(true)
+
+func newColor(enabled bool) *color.Color {
+	c := color.New()
+	if enabled {
+		c.Enable()
+	} else {
+		c.Disable()
+	}
+	return c
+}
+
+func levelColor(c *color.Color, level log.Level, s string) string {
+	switch level {
+	case log.DebugLevel, log.TraceLevel:
+		return c.Grey(s)
+	case log.InfoLevel:
+		return c.Green(s)
+	case log.WarnLevel:
+		return c.Yellow(s)
+	}
+	return c.Red(s)
+}
+
+// LogfmtFormatter writes key=value lines, e.g.
+//
+//	time=2017-06-01T10:00:00Z level=info msg="charged" invoice=7 topic=billing
+type LogfmtFormatter struct {
+	FieldMap FieldMap
+}
+
+func (f *LogfmtFormatter) Format(e *log.Entry) ([]byte, error) {
+	m := f.FieldMap.withDefaults()
+
+	var b bytes.Buffer
+	writeLogfmt(&b, m.Time, e.Time.Format(m.TimeFormat))
+	writeLogfmt(&b, m.Level, e.Level.String())
+	writeLogfmt(&b, m.Message, e.Message)
+	for _, k := range sortedKeys(e.Data) {
+		writeLogfmt(&b, k, e.Data[k])
+	}
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+func writeLogfmt(b *bytes.Buffer, key string, value interface{}) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(logfmtValue(value))
+}
+
+func logfmtValue(value interface{}) string {
+	s := stringValue(value)
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// ECSFormatter writes Elastic Common Schema JSON lines, the topic, caller,
+// stack, error, request id and user fields are mapped to their ECS names
+type ECSFormatter struct{}
+
+var ecsFields = map[string]string{
+	"topic":      "log.logger",
+	"func":       "log.origin.function",
+	"stack":      "error.stack_trace",
+	log.ErrorKey: "error.message",
+	"request_id": "http.request.id",
+	"user":       "user.name",
+}
+
+func (f *ECSFormatter) Format(e *log.Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(e.Data)+4)
+	for k, v := range e.Data {
+		if name, ok := ecsFields[k]; ok {
+			k = name
+		}
+		data[k] = jsonValue(v)
+	}
+	data["@timestamp"] = e.Time.UTC().Format("2006-01-02T15:04:05.000Z")
+	data["log.level"] = e.Level.String()
+	data["message"] = e.Message
+	data["ecs.version"] = ecsVersion
+
+	return marshalLine(data)
+}
+
+// GELFFormatter writes Graylog Extended Log Format 1.1 messages, one per line
+type GELFFormatter struct {
+	// Host defaults to the hostname
+	Host string
+}
+
+var hostname, _ = os.Hostname()
+
+func (f *GELFFormatter) Format(e *log.Entry) ([]byte, error) {
+	host := f.Host
+	if host == "" {
+		host = hostname
+	}
+
+	data := make(map[string]interface{}, len(e.Data)+6)
+	for k, v := range e.Data {
+		if k == "id" {
+			k = "id_"
+		}
+		switch v := v.(type) {
+		case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			data["_"+k] = v
+		default:
+			data["_"+k] = stringValue(v)
+		}
+	}
+
+	data["version"] = "1.1"
+	data["host"] = host
+	data["short_message"] = e.Message
+	if i := strings.IndexByte(e.Message, '\n'); i >= 0 {
+		data["short_message"] = e.Message[:i]
+		data["full_message"] = e.Message
+	}
+	data["timestamp"] = float64(e.Time.UnixNano()/int64(time.Millisecond)) / 1000
+	data["level"] = syslogSeverity(e.Level)
+
+	return marshalLine(data)
+}
+
+// syslogSeverity maps level to the syslog severity used by GELF and syslog
+func syslogSeverity(level log.Level) int {
+	switch level {
+	case log.PanicLevel:
+		return 0
+	case log.FatalLevel:
+		return 2
+	case log.ErrorLevel:
+		return 3
+	case log.WarnLevel:
+		return 4
+	case log.InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+func marshalLine(data map[string]interface{}) ([]byte, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("log: marshal entry: %v", err)
+	}
+	return append(b, '\n'), nil
+}
+
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
+
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedKeys(data log.Fields) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func testEntry() *log.Entry {
+	return &log.Entry{
+		Time:    time.Date(2017, 6, 1, 10, 0, 0, 0, time.UTC),
+		Level:   log.InfoLevel,
+		Message: "charged card\nretry 1",
+		Data: log.Fields{
+			"topic":      "billing",
+			"invoice":    7,
+			"id":         "inv-7",
+			"error":      errors.New("declined"),
+			"request_id": "req-1",
+		},
+	}
+}
+
+func format(t *testing.T, name string, m FieldMap) string {
+	f, err := NewFormatter(name, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.Format(testEntry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func decode(t *testing.T, s string) map[string]interface{} {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		t.Fatalf("invalid json %q: %v", s, err)
+	}
+	return data
+}
+
+func TestJSONFieldMap(t *testing.T) {
+	data := decode(t, format(t, FormatJSON, FieldMap{Message: "message", Time: "@t", TimeFormat: "2006-01-02"}))
+	if data["message"] != "charged card\nretry 1" || data["@t"] != "2017-06-01" || data["level"] != "info" {
+		t.Errorf("field map should rename keys, got %v", data)
+	}
+}
+
+func TestLogfmt(t *testing.T) {
+	got := format(t, FormatLogfmt, FieldMap{})
+	want := `time=2017-06-01T10:00:00Z level=info msg="charged card\nretry 1" error=declined id=inv-7 invoice=7 request_id=req-1 topic=billing` + "\n"
+	if got != want {
+		t.Errorf("unexpected logfmt\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	got := format(t, FormatText, FieldMap{})
+	want := "10:00:00.000 INFO  [billing] charged card\nretry 1 error=declined id=inv-7 invoice=7 request_id=req-1\n"
+	if got != want {
+		t.Errorf("unexpected text\n got %q\nwant %q", got, want)
+	}
+
+	colored, _ := (&TextFormatter{Color: true}).Format(testEntry())
+	if !strings.Contains(string(colored), "\x1b[") {
+		t.Error("colored text should contain escape codes")
+	}
+}
+
+func TestECS(t *testing.T) {
+	data := decode(t, format(t, FormatECS, FieldMap{Message: "ignored"}))
+	expected := map[string]interface{}{
+		"@timestamp":      "2017-06-01T10:00:00.000Z",
+		"log.level":       "info",
+		"log.logger":      "billing",
+		"message":         "charged card\nretry 1",
+		"error.message":   "declined",
+		"http.request.id": "req-1",
+		"ecs.version":     ecsVersion,
+	}
+	for k, v := range expected {
+		if data[k] != v {
+			t.Errorf("%s should be %v, got %v", k, v, data[k])
+		}
+	}
+}
+
+func TestGELF(t *testing.T) {
+	f := &GELFFormatter{Host: "web-1"}
+	b, _ := f.Format(testEntry())
+	data := decode(t, string(b))
+
+	expected := map[string]interface{}{
+		"version":       "1.1",
+		"host":          "web-1",
+		"short_message": "charged card",
+		"full_message":  "charged card\nretry 1",
+		"timestamp":     float64(1496311200),
+		"level":         float64(6),
+		"_topic":        "billing",
+		"_invoice":      float64(7),
+		"_id_":          "inv-7",
+		"_error":        "declined",
+	}
+	for k, v := range expected {
+		if data[k] != v {
+			t.Errorf("%s should be %v, got %v", k, v, data[k])
+		}
+	}
+	if _, ok := data["_id"]; ok {
+		t.Error("_id is reserved by GELF")
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	var text, js strings.Builder
+	SetOutputs(
+		NewOutput(&text, &TextFormatter{}),
+		NewOutput(&js, &log.JSONFormatter{}),
+	)
+	defer SetOutputs(NewOutput(os.Stderr, &log.JSONFormatter{}))
+
+	Topic("billing").Infof("paid")
+
+	if !strings.Contains(text.String(), "INFO  [billing] paid") {
+		t.Errorf("unexpected text output %q", text.String())
+	}
+	if decode(t, js.String())["msg"] != "paid" {
+		t.Errorf("unexpected json output %q", js.String())
+	}
+}
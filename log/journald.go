@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// JournalSocket is the socket of the journald native protocol
+const JournalSocket = "/run/systemd/journal/socket"
+
+// Journald sends entries to journald with its native protocol, the fields of
+// an entry become journal fields, e.g. request_id is REQUEST_ID, and the
+// caller is CODE_FILE, CODE_LINE and CODE_FUNC. The format of the output is
+// not used, MESSAGE is the message of the entry.
+//
+// Entries larger than a datagram of the socket are not sent.
+type Journald struct {
+	mu         sync.RWMutex
+	conn       *conn
+	identifier string
+}
+
+// NewJournald returns a Journald sending to the socket at path, "" for
+// JournalSocket
+func NewJournald(path string) *Journald {
+	if path == "" {
+		path = JournalSocket
+	}
+	return &Journald{conn: newConn("unixgram", path), identifier: filepath.Base(os.Args[0])}
+}
+
+// SetIdentifier sets SYSLOG_IDENTIFIER, the program name by default
+func (j *Journald) SetIdentifier(identifier string) {
+	j.mu.Lock()
+	j.identifier = identifier
+	j.mu.Unlock()
+}
+
+// Write sends b as the message of an info entry
+func (j *Journald) Write(b []byte) (int, error) {
+	var buf bytes.Buffer
+	j.header(&buf, log.InfoLevel)
+	writeJournalField(&buf, "MESSAGE", string(bytes.TrimRight(b, "\n")))
+
+	if err := j.conn.send(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// WriteEntry sends the message and fields of e
+func (j *Journald) WriteEntry(e *log.Entry, _ []byte) error {
+	var buf bytes.Buffer
+	j.header(&buf, e.Level)
+	writeJournalField(&buf, "MESSAGE", e.Message)
+
+	for _, k := range sortedKeys(e.Data) {
+		v := stringValue(e.Data[k])
+		switch k {
+		case "path":
+			if i := strings.LastIndexByte(v, ':'); i > 0 {
+				writeJournalField(&buf, "CODE_FILE", v[:i])
+				writeJournalField(&buf, "CODE_LINE", v[i+1:])
+				continue
+			}
+		case "func":
+			writeJournalField(&buf, "CODE_FUNC", v)
+			continue
+		}
+		if name := journalName(k); name != "" {
+			writeJournalField(&buf, name, v)
+		}
+	}
+
+	return j.conn.send(buf.Bytes())
+}
+
+// Close closes the socket
+func (j *Journald) Close() error {
+	return j.conn.close()
+}
+
+func (j *Journald) header(buf *bytes.Buffer, level log.Level) {
+	j.mu.RLock()
+	identifier := j.identifier
+	j.mu.RUnlock()
+
+	writeJournalField(buf, "PRIORITY", strconv.Itoa(syslogSeverity(level)))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", identifier)
+}
+
+// writeJournalField writes KEY=value, or with its length when value spans
+// several lines
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalName returns key as a journal field name, upper case letters,
+// digits and underscores not starting with an underscore or a digit, as
+// these are reserved for journald
+func journalName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
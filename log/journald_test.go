@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parseJournal decodes a datagram of the journald native protocol
+func parseJournal(t *testing.T, b []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			t.Fatalf("unterminated field %q", b)
+		}
+		line := b[:i]
+		if j := bytes.IndexByte(line, '='); j >= 0 {
+			fields[string(line[:j])] = string(line[j+1:])
+			b = b[i+1:]
+			continue
+		}
+
+		n := binary.LittleEndian.Uint64(b[i+1 : i+9])
+		fields[string(line)] = string(b[i+9 : i+9+int(n)])
+		b = b[i+9+int(n)+1:]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "log")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.sock")
+
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+
+	j := NewJournald(path)
+	defer j.Close()
+	j.SetIdentifier("shop")
+
+	e := logContext("billing").WithFields(log.Fields{
+		"request_id": "req-1",
+		"path":       "/src/app/billing.go:42",
+		"func":       "billing.Charge",
+		"stack":      "billing.Charge billing/billing.go:42\nmain.main app/main.go:9",
+		"_uid":       0,
+	})
+	e.Level, e.Message = log.ErrorLevel, "charge failed"
+	if err := j.WriteEntry(e, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got := parseJournal(t, []byte(readDatagram(t, pc)))
+	want := map[string]string{
+		"MESSAGE":           "charge failed",
+		"PRIORITY":          "3",
+		"SYSLOG_IDENTIFIER": "shop",
+		"TOPIC":             "billing",
+		"REQUEST_ID":        "req-1",
+		"CODE_FILE":         "/src/app/billing.go",
+		"CODE_LINE":         "42",
+		"CODE_FUNC":         "billing.Charge",
+		"STACK":             "billing.Charge billing/billing.go:42\nmain.main app/main.go:9",
+		"UID":               "0",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected fields %v", got)
+	}
+}
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// levels holds the default level and the levels set per topic, topics
+// without one use the default
+var levels = &levelState{
+	level:   log.InfoLevel,
+	topics:  make(map[string]log.Level),
+	reverts: make(map[string]*revert),
+}
+
+type levelState struct {
+	mu         sync.RWMutex
+	level      log.Level
+	topics     map[string]log.Level
+	reverts    map[string]*revert
+	autoRevert time.Duration
+}
+
+// revert restores the level a topic had before a temporary change
+type revert struct {
+	timer *time.Timer
+	level log.Level
+	set   bool
+}
+
+// SetLevel sets the default level, defaults to info
+func SetLevel(level log.Level) {
+	levels.change("", level, true, 0)
+}
+
+// GetLevel returns the default level
+func GetLevel() log.Level {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levels.level
+}
+
+// SetTopicLevel sets the level of topic, overriding the default level
+func SetTopicLevel(topic string, level log.Level) {
+	levels.change(topic, level, true, 0)
+}
+
+// ResetTopicLevel makes topic use the default level again
+func ResetTopicLevel(topic string) {
+	levels.change(topic, 0, false, 0)
+}
+
+// TopicLevel returns the level entries of topic are written at
+func TopicLevel(topic string) log.Level {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levels.get(topic)
+}
+
+// TopicLevels returns the topics with their own level
+func TopicLevels() map[string]log.Level {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	topics := make(map[string]log.Level, len(levels.topics))
+	for k, v := range levels.topics {
+		topics[k] = v
+	}
+	return topics
+}
+
+// SetLevelFor sets the level of topic, "" for the default level, and
+// restores the level it had before after d. Another temporary change of
+// topic keeps the level to restore, a permanent one cancels the revert.
+func SetLevelFor(topic string, level log.Level, d time.Duration) {
+	levels.change(topic, level, true, d)
+}
+
+// SetAutoRevert makes the changes of StepLevel and LevelHandler revert
+// after d unless given their own duration, zero keeps them
+func SetAutoRevert(d time.Duration) {
+	levels.mu.Lock()
+	levels.autoRevert = d
+	levels.mu.Unlock()
+}
+
+// StepLevel moves the default level n steps towards debug, or towards panic
+// when n is negative, and returns the new level. The change is reverted
+// after the SetAutoRevert duration.
+func StepLevel(n int) log.Level {
+	levels.mu.RLock()
+	level := int(levels.level) + n
+	d := levels.autoRevert
+	levels.mu.RUnlock()
+
+	if level < int(log.PanicLevel) {
+		level = int(log.PanicLevel)
+	}
+	if level > int(log.DebugLevel) {
+		level = int(log.DebugLevel)
+	}
+
+	levels.change("", log.Level(level), true, d)
+	return log.Level(level)
+}
+
+// Enabled reports whether entries of topic at level are written
+func Enabled(topic string, level log.Level) bool {
+	return TopicLevel(topic) >= level
+}
+
+func (s *levelState) get(topic string) log.Level {
+	if level, ok := s.topics[topic]; ok {
+		return level
+	}
+	return s.level
+}
+
+// change sets, or with set false removes, the level of topic and schedules
+// the revert when d is positive
+func (s *levelState) change(topic string, level log.Level, set bool, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := &revert{set: true}
+	if r, ok := s.reverts[topic]; ok {
+		r.timer.Stop()
+		delete(s.reverts, topic)
+		prev = r
+	} else if topic == "" {
+		prev.level = s.level
+	} else {
+		prev.level, prev.set = s.topics[topic]
+	}
+
+	s.apply(topic, level, set)
+
+	if d > 0 {
+		r := &revert{level: prev.level, set: prev.set}
+		r.timer = time.AfterFunc(d, func() { s.revert(topic, r) })
+		s.reverts[topic] = r
+	}
+}
+
+func (s *levelState) revert(topic string, r *revert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reverts[topic] != r {
+		return
+	}
+	delete(s.reverts, topic)
+	s.apply(topic, r.level, r.set)
+}
+
+// apply must be called with mu held, the logrus level is set to the most
+// verbose level so it does not filter entries of verbose topics
+func (s *levelState) apply(topic string, level log.Level, set bool) {
+	switch {
+	case topic == "":
+		s.level = level
+	case set:
+		s.topics[topic] = level
+	default:
+		delete(s.topics, topic)
+	}
+
+	max := s.level
+	for _, l := range s.topics {
+		if l > max {
+			max = l
+		}
+	}
+	logger.SetLevel(max)
+}
+
+// SetLevels replaces the default level and all topic levels, cancelling the
+// pending reverts, e.g. when the config is reloaded
+func SetLevels(level log.Level, topics map[string]log.Level) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+
+	for topic, r := range levels.reverts {
+		r.timer.Stop()
+		delete(levels.reverts, topic)
+	}
+	levels.topics = make(map[string]log.Level, len(topics))
+	for k, v := range topics {
+		levels.topics[k] = v
+	}
+	levels.apply("", level, true)
+}
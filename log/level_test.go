@@ -0,0 +1,96 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestTopicLevel(t *testing.T) {
+	defer SetLevels(InfoLevelLog, nil)
+
+	entries := capture(t, func() {
+		SetLevels(WarnLevelLog, map[string]log.Level{"sql": DebugLevelLog})
+		Topic("sql").Debugf("select")
+		Topic("http").Infof("dropped")
+		New(InfoLevelLog, "http", "dropped")
+		Topic("http").Warnf("kept")
+	})
+
+	if len(entries) != 2 || entries[0]["msg"] != "select" || entries[1]["msg"] != "kept" {
+		t.Errorf("topic level should override the default level, got %v", entries)
+	}
+
+	ResetTopicLevel("sql")
+	if Enabled("sql", DebugLevelLog) {
+		t.Error("reset topic should use the default level")
+	}
+}
+
+func TestSetLevelFor(t *testing.T) {
+	defer SetLevels(InfoLevelLog, nil)
+	SetLevels(InfoLevelLog, nil)
+
+	SetLevelFor("", DebugLevelLog, 20*time.Millisecond)
+	SetLevelFor("sql", DebugLevelLog, 20*time.Millisecond)
+	SetLevelFor("sql", WarnLevelLog, 20*time.Millisecond)
+	if GetLevel() != DebugLevelLog || TopicLevel("sql") != WarnLevelLog {
+		t.Fatal("levels should change right away")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if GetLevel() != InfoLevelLog {
+		t.Errorf("default level should revert to info, got %s", GetLevel())
+	}
+	if _, ok := TopicLevels()["sql"]; ok {
+		t.Error("topic level should revert to unset")
+	}
+
+	SetLevelFor("", ErrorLevelLog, 20*time.Millisecond)
+	SetLevel(WarnLevelLog)
+	time.Sleep(40 * time.Millisecond)
+	if GetLevel() != WarnLevelLog {
+		t.Error("permanent change should cancel the revert")
+	}
+}
+
+func TestStepLevel(t *testing.T) {
+	defer SetLevels(InfoLevelLog, nil)
+	SetLevels(InfoLevelLog, nil)
+
+	if StepLevel(1) != DebugLevelLog || StepLevel(1) != DebugLevelLog {
+		t.Error("step up should stop at debug")
+	}
+	if StepLevel(-2) != WarnLevelLog || StepLevel(-10) != PanicLevelLog {
+		t.Error("step down should stop at panic")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer SetLevels(InfoLevelLog, nil)
+	SetLevels(InfoLevelLog, nil)
+	SetOutput(new(strings.Builder))
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"topic":"sql","level":"debug","revert":"1h"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := LevelHandler(e.NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Body.String() != `{"level":"info","topics":{"sql":"debug"}}`+"\n" {
+		t.Errorf("unexpected response %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"loud"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	err := LevelHandler(e.NewContext(req, httptest.NewRecorder()))
+	if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusBadRequest {
+		t.Errorf("invalid level should respond 400, got %v", err)
+	}
+}
@@ -2,41 +2,56 @@ package log
 
 import (
 	"fmt"
-	"runtime"
+	"io"
+	"io/ioutil"
+	"os"
 	"sync"
 
+	"github.com/maps90/go-core/report"
 	log "github.com/sirupsen/logrus"
-	"github.com/evalphobia/logrus_sentry"
 )
 
 const (
+	DebugLevelLog = log.DebugLevel
+	WarnLevelLog  = log.WarnLevel
 	InfoLevelLog  = log.InfoLevel
 	ErrorLevelLog = log.ErrorLevel
 	FatalLevelLog = log.FatalLevel
 	PanicLevelLog = log.PanicLevel
 )
 
-var logger *log.Logger
+// Fields is a set of key/value pairs attached to a log entry
+type Fields map[string]interface{}
+
+// DefaultTopic is the topic of the package level functions
+const DefaultTopic = "go-core"
+
+// logger is usable before Init, Init only adds hooks to it. Entries are
+// written by the outputs hook, see SetOutputs.
+var logger = newLogrus()
+var outputs = &outputHook{outputs: []*Output{NewOutput(os.Stderr, &log.JSONFormatter{})}}
 var once sync.Once
-var topic = "go-core"
 
-func init() {
-	log.SetFormatter(&log.JSONFormatter{})
+func newLogrus() *log.Logger {
+	l := log.New()
+	l.Out = ioutil.Discard
+	l.Formatter = discardFormatter{}
+	l.Hooks.Add(redactHook{})
+	l.Hooks.Add(outputs)
+	l.Hooks.Add(reportHook{})
+	return l
 }
 
 func getLoggerInstance(dsn string, sentry bool) *log.Logger {
 	once.Do(func() {
-		logger = log.New()
-
 		if sentry {
-			// implement hook for sentry
-			if hook, err := logrus_sentry.NewSentryHook(dsn, []log.Level{
-				log.PanicLevel,
-				log.FatalLevel,
-				log.ErrorLevel,
-			}); err == nil {
-				logger.Hooks.Add(hook)
+			// error entries are sent by the report hook
+			s, err := report.NewSentry(dsn)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "log: %v\n", err)
+				return
 			}
+			report.Set(s)
 		}
 	})
 
@@ -44,18 +59,60 @@ func getLoggerInstance(dsn string, sentry bool) *log.Logger {
 }
 
 func logContext(topic string) *log.Entry {
-	return logger.WithFields(log.Fields{
-		"topic": topic,
-		"path":  getCtx(),
-	})
+	return logger.WithField("topic", topic)
 }
 
 func Init(dsn string, sentry bool) {
 	logger = getLoggerInstance(dsn, sentry)
 }
 
+// SetOutput writes entries as JSON to w only, defaults to stderr
+func SetOutput(w io.Writer) {
+	SetOutputs(NewOutput(w, &log.JSONFormatter{}))
+}
+
+// SetOutputs replaces the outputs entries are written to
+func SetOutputs(o ...*Output) {
+	outputs.set(o)
+}
+
 func New(level log.Level, topic string, message ...interface{}) {
-	entry := logContext(topic)
+	if enabled(topic, level) {
+		emit(logContext(topic), topic, level, fmt.Sprint(message...))
+	}
+}
+
+// NewWithFields works like New and attaches fields to the entry
+func NewWithFields(level log.Level, topic string, fields Fields, message ...interface{}) {
+	if enabled(topic, level) {
+		emit(logContext(topic).WithFields(log.Fields(fields)), topic, level, fmt.Sprint(message...))
+	}
+}
+
+// Debugf logs to the default topic, see Topic for other topics
+func Debugf(format string, args ...interface{}) {
+	std.Debugf(format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	std.Infof(format, args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	std.Warnf(format, args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	std.Errorf(format, args...)
+}
+
+// enabled is Enabled except fatal and panic entries are always written as
+// they stop the program
+func enabled(topic string, level log.Level) bool {
+	return level <= log.FatalLevel || Enabled(topic, level)
+}
+
+func write(entry *log.Entry, level log.Level, message ...interface{}) {
 	switch level {
 	case log.DebugLevel:
 		entry.Debug(message...)
@@ -71,11 +128,3 @@ func New(level log.Level, topic string, message ...interface{}) {
 		entry.Panic(message...)
 	}
 }
-
-func getCtx() string {
-	pc := make([]uintptr, 10)
-	runtime.Callers(2, pc)
-	f := runtime.FuncForPC(pc[2])
-	file, line := f.FileLine(pc[2])
-	return fmt.Sprintf("%s:%d", file, line)
-}
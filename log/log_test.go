@@ -0,0 +1,96 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func capture(t *testing.T, fn func()) []map[string]interface{} {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetLevel(DebugLevelLog)
+	defer SetLevel(InfoLevelLog)
+
+	fn()
+	// waits for the entries being written, e.g. by the sampler
+	SetOutput(ioutil.Discard)
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid entry %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestDefaultLogger(t *testing.T) {
+	entries := capture(t, func() {
+		New(InfoLevelLog, "legacy", "before init")
+		Debugf("debug %d", 1)
+		Topic("billing").With(Fields{"invoice": 7}).Warnf("late %s", "payment")
+	})
+
+	if len(entries) != 3 {
+		t.Fatalf("should write 3 entries, got %d", len(entries))
+	}
+	if entries[0]["topic"] != "legacy" || entries[0]["msg"] != "before init" {
+		t.Errorf("unexpected legacy entry %v", entries[0])
+	}
+	if entries[1]["topic"] != DefaultTopic || entries[1]["msg"] != "debug 1" || entries[1]["level"] != "debug" {
+		t.Errorf("unexpected default entry %v", entries[1])
+	}
+	if entries[2]["topic"] != "billing" || entries[2]["invoice"] != float64(7) || entries[2]["level"] != "warning" {
+		t.Errorf("unexpected topic entry %v", entries[2])
+	}
+}
+
+func TestWith(t *testing.T) {
+	parent := Topic("a").With(Fields{"x": 1})
+	child := parent.With(Fields{"y": 2}).Topic("b")
+
+	if len(parent.Fields()) != 1 || len(child.Fields()) != 2 || child.topic != "b" {
+		t.Error("child loggers should not change their parent")
+	}
+
+	entries := capture(t, func() {
+		Topic("a").With(Fields{"topic": "spoofed"}).Info("x")
+	})
+	if entries[0]["topic"] != "a" {
+		t.Error("fields should not override the topic")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != std {
+		t.Error("empty context should return the default logger")
+	}
+
+	ctx := WithUser(WithRequestID(context.Background(), "req-1"), "alice")
+	fields := FromContext(ctx).Fields()
+	if fields["request_id"] != "req-1" || fields["user"] != "alice" {
+		t.Errorf("context values should become fields, got %v", fields)
+	}
+
+	l := Topic("http")
+	if FromContext(NewContext(ctx, l)) != l {
+		t.Error("stored logger should be returned")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	entries := capture(t, func() {
+		Topic("auth").With(Fields{"password": "s3cret", "email": "john@example.com"}).Infof("card %s", "4111111111111111")
+	})
+
+	e := entries[0]
+	if e["password"] != "[REDACTED]" || e["email"] != "j***@example.com" || e["msg"] != "card ************1111" {
+		t.Errorf("sensitive values should be masked, got %v", e)
+	}
+}
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maps90/go-core/report"
+	log "github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+var std = Topic(DefaultTopic)
+
+// Logger writes leveled entries under a topic with a set of fields, e.g.
+//
+//	l := log.Topic("billing").With(log.Fields{"invoice": id})
+//	l.Infof("charged %d", amount)
+type Logger struct {
+	topic  string
+	fields Fields
+	// ctx holds the report scope the entries are breadcrumbs of
+	ctx context.Context
+}
+
+// Topic returns a Logger for topic
+func Topic(topic string) *Logger {
+	return &Logger{topic: topic}
+}
+
+// With returns a Logger for the default topic with fields
+func With(fields Fields) *Logger {
+	return std.With(fields)
+}
+
+// Topic returns a copy of l writing to topic
+func (l *Logger) Topic(topic string) *Logger {
+	return &Logger{topic: topic, fields: l.fields, ctx: l.ctx}
+}
+
+// With returns a child of l with fields added
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{topic: l.topic, fields: merged, ctx: l.ctx}
+}
+
+// Fields returns a copy of the fields of l
+func (l *Logger) Fields() Fields {
+	fields := make(Fields, len(l.fields))
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	return fields
+}
+
+func (l *Logger) entry() *log.Entry {
+	e := logger.WithFields(log.Fields(l.fields)).WithField("topic", l.topic)
+	if l.ctx != nil {
+		e = e.WithContext(l.ctx)
+	}
+	return e
+}
+
+func (l *Logger) log(level log.Level, args ...interface{}) {
+	if enabled(l.topic, level) {
+		emit(l.entry(), l.topic, level, fmt.Sprint(args...))
+	}
+}
+
+func (l *Logger) logf(level log.Level, format string, args ...interface{}) {
+	if enabled(l.topic, level) {
+		emit(l.entry(), l.topic, level, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	l.log(log.DebugLevel, args...)
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	l.log(log.InfoLevel, args...)
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	l.log(log.WarnLevel, args...)
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	l.log(log.ErrorLevel, args...)
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(log.DebugLevel, format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(log.InfoLevel, format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(log.WarnLevel, format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(log.ErrorLevel, format, args...)
+}
+
+// NewContext returns a copy of ctx carrying l, see FromContext
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the Logger stored in ctx by NewContext, or the
+// default logger with the request id and user found in ctx. When ctx has a
+// report scope, see report.NewScope, the entries become its breadcrumbs.
+func FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return std
+	}
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l.scoped(ctx)
+	}
+
+	fields := Fields{}
+	if id := RequestID(ctx); id != "" {
+		fields["request_id"] = id
+	}
+	if user := User(ctx); user != "" {
+		fields["user"] = user
+	}
+	if len(fields) == 0 {
+		return std.scoped(ctx)
+	}
+	return std.With(fields).scoped(ctx)
+}
+
+// scoped returns a copy of l bound to the report scope of ctx, or l when ctx
+// has none
+func (l *Logger) scoped(ctx context.Context) *Logger {
+	if !report.HasScope(ctx) {
+		return l
+	}
+	return &Logger{topic: l.topic, fields: l.fields, ctx: ctx}
+}
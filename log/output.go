@@ -0,0 +1,98 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Output writes entries to a writer in the format of its formatter, each
+// output of the logger has its own format
+type Output struct {
+	mu        sync.Mutex
+	w         io.Writer
+	formatter log.Formatter
+}
+
+// NewOutput returns an Output writing entries formatted by formatter to w
+func NewOutput(w io.Writer, formatter log.Formatter) *Output {
+	return &Output{w: w, formatter: formatter}
+}
+
+func (o *Output) write(e *log.Entry) {
+	b, err := o.formatter.Format(e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log: format entry: %v\n", err)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if ew, ok := o.w.(entryWriter); ok {
+		err = ew.WriteEntry(e, b)
+	} else {
+		_, err = o.w.Write(b)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log: write entry: %v\n", err)
+	}
+}
+
+// entryWriter is a writer of an Output needing the entry along with its
+// formatted bytes, e.g. for its level, see Syslog and Journald
+type entryWriter interface {
+	WriteEntry(e *log.Entry, b []byte) error
+}
+
+// outputHook fans the entries of the logger out to the outputs, directly or
+// through the async queue, the logger itself writes nothing
+type outputHook struct {
+	mu      sync.RWMutex
+	outputs []*Output
+	async   *queue
+}
+
+func (h *outputHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *outputHook) Fire(e *log.Entry) error {
+	h.mu.RLock()
+	q := h.async
+	h.mu.RUnlock()
+
+	if q == nil || !q.push(e) {
+		h.write(e)
+		return nil
+	}
+	if e.Level <= log.FatalLevel {
+		q.flush()
+	}
+	return nil
+}
+
+func (h *outputHook) write(e *log.Entry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, o := range h.outputs {
+		o.write(e)
+	}
+}
+
+// set replaces the outputs once the entries being written are done, so the
+// previous writers can be closed afterwards
+func (h *outputHook) set(outputs []*Output) {
+	h.mu.Lock()
+	h.outputs = outputs
+	h.mu.Unlock()
+}
+
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*log.Entry) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,24 @@
+package log
+
+import (
+	"github.com/maps90/go-core/redact"
+	log "github.com/sirupsen/logrus"
+)
+
+// redactHook masks the message and fields of entries with redact.Default
+// before they reach the outputs and the report hook
+type redactHook struct{}
+
+func (redactHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (redactHook) Fire(e *log.Entry) error {
+	r := redact.Default
+
+	e.Message = r.String(e.Message)
+	for k, v := range e.Data {
+		e.Data[k] = r.Value(k, v)
+	}
+	return nil
+}
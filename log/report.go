@@ -0,0 +1,106 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maps90/go-core/report"
+	log "github.com/sirupsen/logrus"
+)
+
+// reportTimeout bounds reporting fatal and panic entries, they are sent before
+// the program stops
+const reportTimeout = 5 * time.Second
+
+// reportHook sends error entries to report.Get and records every entry as a
+// breadcrumb of the scope of its context, see report.NewScope
+type reportHook struct{}
+
+func (reportHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (reportHook) Fire(e *log.Entry) error {
+	topic, _ := e.Data["topic"].(string)
+
+	if e.Level <= log.ErrorLevel {
+		if _, ok := report.Get().(report.Nop); !ok {
+			ev := event(e, topic)
+			if e.Level <= log.FatalLevel {
+				ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+				if _, err := report.Report(ctx, ev); err != nil {
+					fmt.Fprintf(os.Stderr, "log: %v\n", err)
+				}
+				cancel()
+			} else {
+				report.Enqueue(e.Context, ev)
+			}
+		}
+	}
+
+	data := make(map[string]interface{}, len(e.Data))
+	for k, v := range e.Data {
+		switch k {
+		case "topic", "stack", "path":
+		default:
+			data[k] = v
+		}
+	}
+	report.AddBreadcrumb(e.Context, report.Breadcrumb{
+		Time:     e.Time,
+		Category: topic,
+		Level:    reportLevel(e.Level),
+		Message:  e.Message,
+		Data:     data,
+	})
+	return nil
+}
+
+// event converts e, taken before it is added to the breadcrumbs
+func event(e *log.Entry, topic string) *report.Event {
+	ev := &report.Event{
+		Level:       reportLevel(e.Level),
+		Message:     e.Message,
+		Logger:      topic,
+		Time:        e.Time,
+		Tags:        map[string]string{},
+		Extra:       map[string]interface{}{},
+		Breadcrumbs: report.Breadcrumbs(e.Context),
+	}
+
+	for k, v := range e.Data {
+		switch k {
+		case "topic":
+		case "request_id":
+			ev.Tags[k] = fmt.Sprint(v)
+		case "user":
+			ev.User = &report.User{Username: fmt.Sprint(v)}
+		case log.ErrorKey:
+			if err, ok := v.(error); ok {
+				ev.Error = err
+			} else {
+				ev.Error = errors.New(fmt.Sprint(v))
+			}
+		default:
+			ev.Extra[k] = v
+		}
+	}
+	return ev
+}
+
+func reportLevel(level log.Level) string {
+	switch level {
+	case log.DebugLevel, log.TraceLevel:
+		return report.LevelDebug
+	case log.InfoLevel:
+		return report.LevelInfo
+	case log.WarnLevel:
+		return report.LevelWarning
+	case log.ErrorLevel:
+		return report.LevelError
+	}
+	return report.LevelFatal
+}
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maps90/go-core/report"
+)
+
+type recordingReporter chan *report.Event
+
+func (r recordingReporter) Report(_ context.Context, e *report.Event) (string, error) {
+	r <- e
+	return "id", nil
+}
+
+func TestReportHook(t *testing.T) {
+	events := make(recordingReporter, 1)
+	report.Set(events)
+	defer report.Set(nil)
+	ctx := report.NewScope(context.Background())
+	l := FromContext(ctx).Topic("db")
+
+	capture(t, func() {
+		l.Info("connecting")
+		Topic("db").Info("other request")
+		l.With(Fields{"user": "alice", "request_id": "req-1", "password": "hunter2"}).Errorf("query failed")
+		report.Flush(time.Second)
+	})
+
+	var e *report.Event
+	select {
+	case e = <-events:
+	case <-time.After(time.Second):
+		t.Fatal("error entry should be reported")
+	}
+
+	if e.Level != report.LevelError || e.Message != "query failed" || e.Logger != "db" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e.User == nil || e.User.Username != "alice" || e.Tags["request_id"] != "req-1" {
+		t.Errorf("user and request id should be set, got %+v %v", e.User, e.Tags)
+	}
+	if e.Extra["password"] == "hunter2" {
+		t.Error("fields should be masked before they are reported")
+	}
+	if n := len(e.Breadcrumbs); n == 0 || e.Breadcrumbs[n-1].Message != "connecting" || e.Breadcrumbs[n-1].Category != "db" {
+		t.Errorf("previous entries should be breadcrumbs, got %+v", e.Breadcrumbs)
+	}
+
+	for _, b := range e.Breadcrumbs {
+		if b.Message == "other request" {
+			t.Error("entries outside the scope should not be breadcrumbs")
+		}
+	}
+
+	crumbs := report.Breadcrumbs(ctx)
+	if crumbs[len(crumbs)-1].Message != "query failed" {
+		t.Error("reported entries should become breadcrumbs too")
+	}
+	if e.ID == "" || report.LastEventID(ctx) != e.ID {
+		t.Errorf("event id should be stored in the scope, got %q and %q", e.ID, report.LastEventID(ctx))
+	}
+}
+
+func TestReportHookError(t *testing.T) {
+	e := event(logContext("db").WithError(errors.New("timeout")), "db")
+	if e.Error == nil || e.Error.Error() != "timeout" {
+		t.Errorf("error field should be the error of the event, got %v", e.Error)
+	}
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxCounters bounds the messages tracked by the sampler, the counters of
+// past intervals are removed once it is reached and new messages pass
+// without sampling while it is still full
+const maxCounters = 10000
+
+// Sampling limits identical entries, the same topic, level and message, to
+// the First of each Interval and then 1 in Thereafter, zero drops all of
+// them. Dropped entries are collapsed into one entry with a "repeated" count
+// written at the end of the interval.
+//
+// Error entries pass at least once every ErrorEvery, 1s by default, fatal and
+// panic entries always pass. A zero Interval disables sampling.
+type Sampling struct {
+	First      int           `mapstructure:"first" json:"first"`
+	Thereafter int           `mapstructure:"thereafter" json:"thereafter"`
+	Interval   time.Duration `mapstructure:"interval" json:"interval"`
+	ErrorEvery time.Duration `mapstructure:"error_every" json:"error_every"`
+}
+
+var sampler = &samplerState{
+	topics:   make(map[string]Sampling),
+	counters: make(map[string]*counter),
+}
+
+type samplerState struct {
+	mu       sync.Mutex
+	sampling Sampling
+	topics   map[string]Sampling
+	counters map[string]*counter
+}
+
+type counter struct {
+	end     time.Time
+	n       int
+	passed  time.Time
+	dropped int
+	entry   *log.Entry
+	level   log.Level
+	msg     string
+	timer   *time.Timer
+}
+
+// SetSampling sets the sampling of topic, "" for the topics without their
+// own, a zero Sampling disables it
+func SetSampling(topic string, s Sampling) {
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+
+	if topic == "" {
+		sampler.sampling = s
+	} else if s == (Sampling{}) {
+		delete(sampler.topics, topic)
+	} else {
+		sampler.topics[topic] = s
+	}
+}
+
+// SetSamplings replaces the default sampling and the sampling of all topics
+func SetSamplings(s Sampling, topics map[string]Sampling) {
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+
+	sampler.sampling = s
+	sampler.topics = make(map[string]Sampling, len(topics))
+	for k, v := range topics {
+		sampler.topics[k] = v
+	}
+}
+
+// emit writes entry with its caller unless it is sampled out, the caller is
+// only looked up for the entries that are written
+func emit(entry *log.Entry, topic string, level log.Level, msg string) {
+	withCaller := func() *log.Entry {
+		if fields := callerFields(level); fields != nil {
+			return entry.WithFields(fields)
+		}
+		return entry
+	}
+	if sampler.sample(withCaller, topic, level, msg) {
+		write(withCaller(), level, msg)
+	}
+}
+
+// sample reports whether the entry passes, dropped entries are counted and
+// the first of them is written with their count by flush
+func (s *samplerState) sample(entry func() *log.Entry, topic string, level log.Level, msg string) bool {
+	if level <= log.FatalLevel {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, ok := s.topics[topic]
+	if !ok {
+		cfg = s.sampling
+	}
+	if cfg.Interval <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	key := topic + "\x00" + level.String() + "\x00" + msg
+	c := s.counters[key]
+	if c == nil || !now.Before(c.end) {
+		if c == nil && len(s.counters) >= maxCounters {
+			s.expire(now)
+			if len(s.counters) >= maxCounters {
+				return true
+			}
+		}
+		c = &counter{end: now.Add(cfg.Interval)}
+		s.counters[key] = c
+	}
+
+	c.n++
+	pass := c.n <= cfg.First || (cfg.Thereafter > 0 && (c.n-cfg.First)%cfg.Thereafter == 0)
+	if !pass && level <= log.ErrorLevel {
+		every := cfg.ErrorEvery
+		if every <= 0 {
+			every = time.Second
+		}
+		pass = now.Sub(c.passed) >= every
+	}
+	if pass {
+		c.passed = now
+		return true
+	}
+
+	c.dropped++
+	if c.entry == nil {
+		c.entry, c.level, c.msg = entry(), level, msg
+	}
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.end.Sub(now), func() { s.flush(key, c) })
+	}
+	return false
+}
+
+// expire removes the counters of past intervals, must be called with mu held
+func (s *samplerState) expire(now time.Time) {
+	for key, c := range s.counters {
+		if !now.Before(c.end) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// flush writes the entry collapsing the entries c dropped
+func (s *samplerState) flush(key string, c *counter) {
+	s.mu.Lock()
+	if s.counters[key] == c {
+		delete(s.counters, key)
+	}
+	dropped, entry, level, msg := c.dropped, c.entry, c.level, c.msg
+	c.dropped, c.entry = 0, nil
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		write(entry.WithField("repeated", dropped), level, msg)
+	}
+}
+
+// flushSampler writes the pending repeated entries right away
+func flushSampler() {
+	sampler.mu.Lock()
+	pending := make(map[string]*counter, len(sampler.counters))
+	for key, c := range sampler.counters {
+		if c.timer != nil && c.timer.Stop() {
+			pending[key] = c
+		}
+	}
+	sampler.mu.Unlock()
+
+	for key, c := range pending {
+		sampler.flush(key, c)
+	}
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSampling(t *testing.T) {
+	defer SetSamplings(Sampling{}, nil)
+
+	entries := capture(t, func() {
+		SetSampling("", Sampling{First: 2, Thereafter: 5, Interval: 50 * time.Millisecond})
+		for i := 0; i < 12; i++ {
+			Topic("http").Infof("same")
+		}
+		Topic("http").Infof("other")
+		time.Sleep(80 * time.Millisecond)
+	})
+
+	var same, other, repeated int
+	for _, e := range entries {
+		switch {
+		case e["repeated"] != nil:
+			repeated = int(e["repeated"].(float64))
+		case e["msg"] == "same":
+			same++
+		case e["msg"] == "other":
+			other++
+		}
+	}
+	// 1, 2 pass as first, 7 and 12 as 1 in 5
+	if same != 4 || other != 1 {
+		t.Errorf("expected 4 sampled entries and 1 other, got %d and %d", same, other)
+	}
+	if repeated != 8 {
+		t.Errorf("dropped entries should be collapsed with their count, got %d", repeated)
+	}
+}
+
+func TestSamplingTopic(t *testing.T) {
+	defer SetSamplings(Sampling{}, nil)
+
+	entries := capture(t, func() {
+		SetSampling("datasource", Sampling{First: 1, Interval: time.Hour, ErrorEvery: 30 * time.Millisecond})
+		for i := 0; i < 5; i++ {
+			New(ErrorLevelLog, "datasource", "DB Connection Error: ", "refused")
+			Topic("http").Infof("unsampled")
+		}
+		time.Sleep(40 * time.Millisecond)
+		New(ErrorLevelLog, "datasource", "DB Connection Error: ", "refused")
+		Close()
+	})
+
+	var errors, unsampled, repeated int
+	for _, e := range entries {
+		switch {
+		case e["repeated"] != nil:
+			repeated = int(e["repeated"].(float64))
+		case e["topic"] == "datasource":
+			errors++
+		default:
+			unsampled++
+		}
+	}
+	if unsampled != 5 {
+		t.Errorf("other topics should not be sampled, got %d", unsampled)
+	}
+	if errors != 2 {
+		t.Errorf("errors should pass at the minimum rate, got %d", errors)
+	}
+	if repeated != 4 {
+		t.Errorf("Close should flush the repeated count, got %d", repeated)
+	}
+}
+
+func TestSamplingMaxCounters(t *testing.T) {
+	s := &samplerState{
+		sampling: Sampling{First: 1, Interval: time.Hour},
+		topics:   make(map[string]Sampling),
+		counters: make(map[string]*counter),
+	}
+	entry := func() *log.Entry { return logContext("http") }
+
+	for i := 0; i < maxCounters+100; i++ {
+		if !s.sample(entry, "http", InfoLevelLog, strconv.Itoa(i)) {
+			t.Fatalf("first entry of message %d should pass", i)
+		}
+	}
+	if len(s.counters) != maxCounters {
+		t.Errorf("counters should stop at %d, got %d", maxCounters, len(s.counters))
+	}
+	if !s.sample(entry, "http", InfoLevelLog, strconv.Itoa(maxCounters+1)) {
+		t.Error("untracked message should pass")
+	}
+	if s.sample(entry, "http", InfoLevelLog, "0") {
+		t.Error("tracked message should still be sampled")
+	}
+	for _, c := range s.counters {
+		if c.timer != nil {
+			c.timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import "time"
+
+// Shipper sends entries, one per line, to a collector over TCP or UDP, e.g.
+// JSON lines to Logstash or Vector. Each line is a datagram over UDP.
+//
+// A Shipper reconnects when the connection fails, entries are buffered in
+// the meantime, see SetBuffer.
+type Shipper struct {
+	conn *conn
+}
+
+// NewShipper returns a Shipper sending to addr over network, "tcp" or "udp",
+// the connection is dialed on the first write
+func NewShipper(network, addr string) *Shipper {
+	return &Shipper{conn: newConn(network, addr)}
+}
+
+// SetBuffer sets the number of entries buffered while disconnected, the
+// oldest are dropped once full, DefaultBuffer by default
+func (s *Shipper) SetBuffer(n int) {
+	s.conn.setBuffer(n)
+}
+
+// SetTimeout sets the dial and write timeout, DefaultTimeout by default
+func (s *Shipper) SetTimeout(d time.Duration) {
+	s.conn.setTimeout(d)
+}
+
+// Write sends b as one line
+func (s *Shipper) Write(b []byte) (int, error) {
+	line := make([]byte, len(b), len(b)+1)
+	copy(line, b)
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		line = append(line, '\n')
+	}
+
+	if err := s.conn.send(line); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Dropped returns the number of entries dropped as the buffer was full
+// or they were larger than a datagram
+func (s *Shipper) Dropped() uint64 {
+	return s.conn.droppedCount()
+}
+
+// Close sends the buffered entries if it can and closes the connection
+func (s *Shipper) Close() error {
+	return s.conn.close()
+}
@@ -0,0 +1,231 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines accepts connections on l and sends the lines read from them
+func lines(l net.Listener) <-chan string {
+	ch := make(chan string, 100)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				s := bufio.NewScanner(c)
+				for s.Scan() {
+					ch <- s.Text()
+				}
+			}()
+		}
+	}()
+	return ch
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(2 * time.Second):
+		t.Fatal("nothing received")
+	}
+	return ""
+}
+
+func TestShipperTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	ch := lines(l)
+
+	s := NewShipper("tcp", addr)
+	defer s.Close()
+	s.Write([]byte(`{"msg":"one"}` + "\n"))
+	s.Write([]byte(`{"msg":"two"}`))
+	if got := receive(t, ch); got != `{"msg":"one"}` {
+		t.Errorf("unexpected line %q", got)
+	}
+	if got := receive(t, ch); got != `{"msg":"two"}` {
+		t.Errorf("lines should end with a newline, got %q", got)
+	}
+
+	// the collector restarts, the writer reconnects without further writes
+	l.Close()
+	s.conn.mu.Lock()
+	s.conn.c.Close()
+	s.conn.mu.Unlock()
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ch = lines(l)
+
+	s.Write([]byte("lost"))
+	if got := receive(t, ch); got != "lost" {
+		t.Errorf("failed line should be sent again, got %q", got)
+	}
+	s.Write([]byte("three"))
+	if got := receive(t, ch); got != "three" {
+		t.Errorf("unexpected line %q", got)
+	}
+}
+
+func TestShipperBuffer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := NewShipper("tcp", addr)
+	defer s.Close()
+	s.SetBuffer(3)
+	s.Write([]byte("one"))
+	s.Write([]byte("two"))
+	s.Write([]byte("three"))
+	s.Write([]byte("four"))
+	if s.Dropped() != 1 {
+		t.Errorf("oldest line should be dropped, dropped %d", s.Dropped())
+	}
+
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ch := lines(l)
+
+	// Close sends the buffered lines without waiting for the backoff
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"two", "three", "four"} {
+		if got := receive(t, ch); got != want {
+			t.Errorf("buffered lines should be sent in order, got %q want %q", got, want)
+		}
+	}
+}
+
+func TestShipperUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := NewShipper("tcp", addr)
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if _, err := s.Write([]byte("queued")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("writes should only buffer, took %v", elapsed)
+	}
+
+	if err := s.Close(); err == nil {
+		t.Error("Close should return the failure of the last attempt")
+	}
+	if s.Dropped() != 100 {
+		t.Errorf("lines left at Close should be dropped, dropped %d", s.Dropped())
+	}
+	if _, err := s.Write([]byte("closed")); err != errClosed {
+		t.Errorf("write after Close should fail, got %v", err)
+	}
+}
+
+func TestShipperUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	s := NewShipper("udp", pc.LocalAddr().String())
+	defer s.Close()
+	s.Write([]byte(`{"msg":"one"}`))
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != `{"msg":"one"}`+"\n" {
+		t.Errorf("each line should be a datagram, got %q", got)
+	}
+}
+
+func TestShipperOversized(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	s := NewShipper("udp", pc.LocalAddr().String())
+	defer s.Close()
+	s.Write([]byte(strings.Repeat("x", 70000)))
+	s.Write([]byte(`{"msg":"next"}`))
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != `{"msg":"next"}`+"\n" {
+		t.Errorf("entry after an oversized one should be sent, got %q", got)
+	}
+	if s.Dropped() != 1 {
+		t.Errorf("oversized entry should be dropped, dropped %d", s.Dropped())
+	}
+}
+
+func TestConfigureShipper(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ch := lines(l)
+
+	err = Configure(&Config{Outputs: []OutputConfig{{Type: "tcp", Address: l.Addr().String(), Buffer: 10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Topic("ship").Info("shipped")
+	Close()
+
+	if got := receive(t, ch); !strings.Contains(got, `"msg":"shipped"`) || !strings.Contains(got, `"topic":"ship"`) {
+		t.Errorf("entry should be shipped as JSON, got %q", got)
+	}
+
+	if err := Configure(&Config{Outputs: []OutputConfig{{Type: "udp"}}}); err == nil {
+		t.Error("shipper without address should fail")
+	}
+
+	// the outputs built before the invalid one are closed
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		err := Configure(&Config{Outputs: []OutputConfig{{Type: "tcp", Address: l.Addr().String()}, {Type: "udp"}}})
+		if err == nil {
+			t.Fatal("shipper without address should fail")
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before+2 {
+		t.Errorf("failed configs should not leak writers, %d goroutines before and %d after", before, after)
+	}
+}
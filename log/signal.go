@@ -0,0 +1,35 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var signalOnce sync.Once
+
+// HandleLevelSignals steps the default level towards debug on SIGUSR1 and
+// towards panic on SIGUSR2, see StepLevel. Calls after the first do nothing.
+func HandleLevelSignals() {
+	signalOnce.Do(handleLevelSignals)
+}
+
+func handleLevelSignals() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for s := range sig {
+			n := 1
+			if s == syscall.SIGUSR2 {
+				n = -1
+			}
+			level := StepLevel(n)
+			Topic(DefaultTopic).Warnf("log level changed to %s by %s", level, s)
+		}
+	}()
+}
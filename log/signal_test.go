@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	defer SetLevels(InfoLevelLog, nil)
+	SetLevels(InfoLevelLog, nil)
+	SetOutput(new(strings.Builder))
+
+	HandleLevelSignals()
+	HandleLevelSignals()
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+
+	for deadline := time.Now().Add(2 * time.Second); GetLevel() == InfoLevelLog; {
+		if time.Now().After(deadline) {
+			t.Fatal("level should change on SIGUSR2")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if GetLevel() != WarnLevelLog {
+		t.Errorf("signal should step the level once, got %s", GetLevel())
+	}
+}
@@ -0,0 +1,4 @@
+package log
+
+// HandleLevelSignals does nothing, windows has no SIGUSR1 and SIGUSR2
+func HandleLevelSignals() {}
@@ -0,0 +1,166 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SyslogSocket is the local syslog socket used when no address is given
+const SyslogSocket = "/dev/log"
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// ParseFacility returns the syslog facility named name, e.g. daemon or local0
+func ParseFacility(name string) (int, error) {
+	if name == "" {
+		return 1, nil
+	}
+	for i, f := range facilities {
+		if f == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("log: unknown syslog facility %q", name)
+}
+
+// Syslog sends entries as RFC 5424 messages. The topic of an entry is the
+// MSGID and the entry formatted by the output is the MSG.
+//
+// Messages are framed by octet counting, RFC 6587, over "tcp" and "unix"
+// and sent one per datagram over "udp" and "unixgram". Like a Shipper, a
+// Syslog reconnects and buffers messages in the meantime.
+type Syslog struct {
+	mu       sync.RWMutex
+	conn     *conn
+	stream   bool
+	facility int
+	hostname string
+	appName  string
+}
+
+// NewSyslog returns a Syslog sending to addr over network, "udp", "tcp",
+// "unix" or "unixgram", both empty for the local SyslogSocket
+func NewSyslog(network, addr string) *Syslog {
+	if network == "" && addr == "" {
+		network, addr = "unixgram", SyslogSocket
+	}
+
+	return &Syslog{
+		conn:     newConn(network, addr),
+		stream:   network == "unix" || strings.HasPrefix(network, "tcp"),
+		facility: 1,
+		hostname: hostname,
+		appName:  filepath.Base(os.Args[0]),
+	}
+}
+
+// SetFacility sets the facility of messages, user by default, see
+// ParseFacility
+func (s *Syslog) SetFacility(facility int) {
+	s.mu.Lock()
+	s.facility = facility
+	s.mu.Unlock()
+}
+
+// SetHostname sets the HOSTNAME of messages, the hostname by default
+func (s *Syslog) SetHostname(name string) {
+	s.mu.Lock()
+	s.hostname = name
+	s.mu.Unlock()
+}
+
+// SetAppName sets the APP-NAME of messages, the program name by default
+func (s *Syslog) SetAppName(name string) {
+	s.mu.Lock()
+	s.appName = name
+	s.mu.Unlock()
+}
+
+// SetBuffer sets the number of messages buffered while disconnected, see
+// Shipper.SetBuffer
+func (s *Syslog) SetBuffer(n int) {
+	s.conn.setBuffer(n)
+}
+
+// SetTimeout sets the dial and write timeout, DefaultTimeout by default
+func (s *Syslog) SetTimeout(d time.Duration) {
+	s.conn.setTimeout(d)
+}
+
+// Write sends b as a message at the info severity
+func (s *Syslog) Write(b []byte) (int, error) {
+	if err := s.conn.send(s.message(log.InfoLevel, time.Now(), "", b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// WriteEntry sends b, the formatted e, at the severity of e
+func (s *Syslog) WriteEntry(e *log.Entry, b []byte) error {
+	topic, _ := e.Data["topic"].(string)
+	return s.conn.send(s.message(e.Level, e.Time, topic, b))
+}
+
+// Dropped returns the number of messages dropped as the buffer was full
+// or they were larger than a datagram
+func (s *Syslog) Dropped() uint64 {
+	return s.conn.droppedCount()
+}
+
+// Close sends the buffered messages if it can and closes the connection
+func (s *Syslog) Close() error {
+	return s.conn.close()
+}
+
+// message formats a RFC 5424 message without structured data
+func (s *Syslog) message(level log.Level, t time.Time, msgID string, b []byte) []byte {
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	s.mu.RLock()
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ",
+		s.facility*8+syslogSeverity(level),
+		t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogName(s.hostname, 255),
+		syslogName(s.appName, 48),
+		os.Getpid(),
+		syslogName(msgID, 32))
+	s.mu.RUnlock()
+
+	msg := append([]byte(header), bytes.TrimRight(b, "\n")...)
+	if s.stream {
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+	return msg
+}
+
+// syslogName returns s as a header field, printable ASCII of at most n
+// characters, or "-" when empty
+func syslogName(s string, n int) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+
+	if len(name) > n {
+		name = name[:n]
+	}
+	if name == "" {
+		return "-"
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func readDatagram(t *testing.T, pc net.PacketConn) string {
+	buf := make([]byte, 64<<10)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	s := NewSyslog("udp", pc.LocalAddr().String())
+	defer s.Close()
+	facility, _ := ParseFacility("local0")
+	s.SetFacility(facility)
+	s.SetHostname("web 1")
+	s.SetAppName("shop")
+
+	e := logContext("billing").WithTime(time.Date(2017, 5, 24, 9, 30, 0, 123456000, time.UTC))
+	e.Level = log.ErrorLevel
+	if err := s.WriteEntry(e, []byte("payment failed\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf("<131>1 2017-05-24T09:30:00.123456Z web_1 shop %d billing - payment failed", os.Getpid())
+	if got := readDatagram(t, pc); got != want {
+		t.Errorf("unexpected message\n got %q\nwant %q", got, want)
+	}
+
+	s.Write([]byte("plain"))
+	if got := readDatagram(t, pc); !regexp.MustCompile(`^<134>1 \S+ web_1 shop \d+ - - plain$`).MatchString(got) {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s := NewSyslog("tcp", l.Addr().String())
+	defer s.Close()
+	s.Write([]byte("one"))
+	s.Write([]byte("two\nlines"))
+
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r := bufio.NewReader(c)
+
+	// messages are framed by their length as they may span lines
+	for _, want := range []string{"one", "two\nlines"} {
+		size, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(size[:len(size)-1])
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(msg); got[len(got)-len(want):] != want || got[:4] != "<14>" {
+			t.Errorf("unexpected message %q", got)
+		}
+	}
+}
+
+func TestSyslogUnix(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "log")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.sock")
+
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+
+	err = Configure(&Config{Outputs: []OutputConfig{{
+		Type: "syslog", Network: "unixgram", Address: path, Format: FormatLogfmt, Facility: "daemon", AppName: "api",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	Topic("http").Warn("slow request")
+	got := readDatagram(t, pc)
+	if !regexp.MustCompile(`^<28>1 \S+ \S+ api \d+ http - .*msg="slow request"`).MatchString(got) {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	if f, err := ParseFacility(""); f != 1 || err != nil {
+		t.Errorf("default facility should be user, got %d %v", f, err)
+	}
+	if f, _ := ParseFacility("local7"); f != 23 {
+		t.Errorf("local7 should be 23, got %d", f)
+	}
+	if _, err := ParseFacility("nope"); err == nil {
+		t.Error("unknown facility should fail")
+	}
+	if err := Configure(&Config{Outputs: []OutputConfig{{Type: "syslog", Facility: "nope"}}}); err == nil {
+		t.Error("config with unknown facility should fail")
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/labstack/echo"
+	"github.com/maps90/go-core/report"
+)
+
+// StatusCoder is implemented by errors that map to an HTTP status, e.g.
+// datasource.ErrStaleObject
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// ErrorHandler converts StatusCoder errors to echo.HTTPError before passing
+// them to next. When an error was reported during the request, e.g. logged
+// at error level, the event id is set in EventIDHeader and stored in the
+// context as "event_id", e.g.
+//
+//	e.HTTPErrorHandler = middleware.ErrorHandler(e.DefaultHTTPErrorHandler)
+func ErrorHandler(next echo.HTTPErrorHandler) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		var coder StatusCoder
+		if errors.As(err, &coder) {
+			err = echo.NewHTTPError(coder.StatusCode(), err.Error())
+		}
+		if id := report.LastEventID(c.Request().Context()); id != "" {
+			c.Set(eventIDKey, id)
+			c.Response().Header().Set(EventIDHeader, id)
+		}
+		next(err, c)
+	}
+}
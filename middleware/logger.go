@@ -7,12 +7,16 @@ import (
 	"strconv"
 	"time"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/labstack/echo"
+	corelog "github.com/maps90/go-core/log"
+	"github.com/maps90/go-core/redact"
 )
 
+// Logger logs each request under the "http" topic and stores the request
+// logger in the request context, handlers get it with log.FromEcho. The
+// sensitive query params of the request URI are masked by redact.Default.
 func Logger(name string) echo.MiddlewareFunc {
-	l := log.StandardLogger()
+	l := corelog.Topic("http")
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -28,15 +32,24 @@ func Logger(name string) echo.MiddlewareFunc {
 				remoteAddr, _, _ = net.SplitHostPort(remoteAddr)
 			}
 
-			entry := l.WithFields(log.Fields{
-				"request": req.RequestURI,
+			entry := l.With(corelog.Fields{
+				"request": redact.Default.URL(req.RequestURI),
 				"method":  req.Method,
 				"remote":  remoteAddr,
+				"route":   c.Path(),
 			})
 
-			if reqID := req.Header.Get("X-Request-Id"); reqID != "" {
-				entry = entry.WithField("request_id", reqID)
+			// the id is set by RequestID, the header is used without it
+			ctx := req.Context()
+			if reqID := corelog.RequestID(ctx); reqID != "" {
+				entry = entry.With(corelog.Fields{"request_id": reqID})
+			} else if reqID = req.Header.Get(echo.HeaderXRequestID); reqID != "" {
+				entry = entry.With(corelog.Fields{"request_id": reqID})
+				ctx = corelog.WithRequestID(ctx, reqID)
 			}
+			ctx = corelog.NewContext(ctx, entry)
+			c.SetRequest(req.WithContext(ctx))
+			entry = corelog.FromContext(ctx)
 
 			entry.Info("started handling request")
 
@@ -48,11 +61,11 @@ func Logger(name string) echo.MiddlewareFunc {
 
 			latency := stop.Sub(start)
 
-			entry.WithFields(log.Fields{
-				"size":        res.Size,
-				"status":      res.Status,
-				"text_status": http.StatusText(res.Status),
-				"took":        strconv.FormatInt(int64(latency), 10),
+			entry.With(corelog.Fields{
+				"size":                           res.Size,
+				"status":                         res.Status,
+				"text_status":                    http.StatusText(res.Status),
+				"took":                           strconv.FormatInt(int64(latency), 10),
 				fmt.Sprintf("#%s.latency", name): stop.Sub(start).String(),
 			}).Info("completed handling request")
 
@@ -2,12 +2,24 @@ package middleware
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 
 	"github.com/labstack/echo"
-	"github.com/getsentry/raven-go"
+	corelog "github.com/maps90/go-core/log"
+	"github.com/maps90/go-core/redact"
+	"github.com/maps90/go-core/report"
 )
 
+// EventIDHeader is the response header carrying the id of the reported panic
+// or error
+const EventIDHeader = "X-Event-Id"
+
+// eventIDKey stores the event id in the echo context
+const eventIDKey = "event_id"
+
+// AppRecover recovers panics, reports them with report.Get and responds with
+// the event id in EventIDHeader, also stored in the context as "event_id"
 func AppRecover(env string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -20,13 +32,33 @@ func AppRecover(env string) echo.MiddlewareFunc {
 					default:
 						err = fmt.Errorf("%v", r)
 					}
-					stack := make([]byte, 4 << 10)
+					stack := make([]byte, 4<<10)
 					length := runtime.Stack(stack, true)
-					c.Logger().Printf("[%s] %s %s\n", "PANIC RECOVER", err, stack[:length])
-					raven.CaptureError(err, map[string]string{
-						"env": env,
-						"error": err.Error(),
-					})
+
+					// panics often carry request payloads, mask them before
+					// they are logged or sent to sentry
+					msg := redact.Default.String(err.Error())
+					c.Logger().Printf("[%s] %s %s\n", "PANIC RECOVER", msg, redact.Default.String(string(stack[:length])))
+					req := c.Request()
+					ev := &report.Event{
+						Level:   report.LevelFatal,
+						Error:   err,
+						Request: req,
+						Tags:    map[string]string{"env": env},
+						Stack:   report.Stack(1),
+					}
+					if user := corelog.User(req.Context()); user != "" {
+						ev.User = &report.User{Username: user}
+					}
+					if id := corelog.RequestID(req.Context()); id != "" {
+						ev.Tags["request_id"] = id
+					}
+					if id, err := report.Report(req.Context(), ev); err != nil {
+						fmt.Fprintf(os.Stderr, "recover: %v\n", err)
+					} else if id != "" {
+						c.Set(eventIDKey, id)
+						c.Response().Header().Set(EventIDHeader, id)
+					}
 					c.Error(err)
 				}
 			}()
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	corelog "github.com/maps90/go-core/log"
+	"github.com/maps90/go-core/report"
+)
+
+// fakeSentry records the ids of the events sent to its store endpoint
+func fakeSentry(t *testing.T) func() []string {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event struct {
+			ID string `json:"event_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("event: %v", err)
+		}
+		mu.Lock()
+		ids = append(ids, event.ID)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	s, err := report.NewSentry(strings.Replace(srv.URL, "://", "://public@", 1) + "/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Set(s)
+	t.Cleanup(func() { report.Set(nil) })
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ids...)
+	}
+}
+
+func TestAppRecover(t *testing.T) {
+	sent := fakeSentry(t)
+
+	var eventID interface{}
+	e := echo.New()
+	e.Use(RequestID())
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			eventID = c.Get("event_id")
+			return err
+		}
+	})
+	e.Use(AppRecover("test"))
+	e.GET("/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("panic should respond 500, got %d", rec.Code)
+	}
+	ids := sent()
+	if len(ids) != 1 || len(ids[0]) != 32 {
+		t.Fatalf("panic should be reported once, got %v", ids)
+	}
+	if id := rec.Header().Get(EventIDHeader); id != ids[0] {
+		t.Errorf("%s should be the reported id %s, got %q", EventIDHeader, ids[0], id)
+	}
+	if eventID != ids[0] {
+		t.Errorf("event_id should be the reported id %s, got %v", ids[0], eventID)
+	}
+}
+
+func TestErrorHandlerEventID(t *testing.T) {
+	sent := fakeSentry(t)
+
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(e.DefaultHTTPErrorHandler)
+	e.Use(RequestID())
+	e.GET("/fail", func(c echo.Context) error {
+		err := errors.New("charge failed")
+		corelog.FromEcho(c).Errorf("billing: %v", err)
+		return err
+	})
+	e.GET("/ok", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/fail", nil))
+	report.Flush(time.Second)
+
+	ids := sent()
+	if len(ids) != 1 {
+		t.Fatalf("error entry should be reported once, got %v", ids)
+	}
+	if id := rec.Header().Get(EventIDHeader); id != ids[0] {
+		t.Errorf("%s should be the id of the logged error %s, got %q", EventIDHeader, ids[0], id)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/ok", nil))
+	if id := rec.Header().Get(EventIDHeader); id != "" {
+		t.Errorf("other requests should not get the event id, got %q", id)
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/labstack/echo"
+	corelog "github.com/maps90/go-core/log"
+	"github.com/maps90/go-core/report"
+)
+
+// RequestID stores the X-Request-Id of the request, or a new id when it has
+// none, in the request context and the response header. It is installed by
+// NewRouter, loggers and datasource.QueryLogger.WithContext read the id with
+// log.RequestID. The context also gets a report scope collecting the
+// breadcrumbs and event ids of the request, see report.NewScope.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if id == "" {
+				id = newRequestID()
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			ctx := report.NewScope(corelog.WithRequestID(req.Context(), id))
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
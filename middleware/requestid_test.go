@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	corelog "github.com/maps90/go-core/log"
+)
+
+func TestRequestID(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestID())
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, corelog.RequestID(c.Request().Context()))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Body.String() != "req-1" || rec.Header().Get(echo.HeaderXRequestID) != "req-1" {
+		t.Errorf("request id should be kept, got %q %q", rec.Body.String(), rec.Header().Get(echo.HeaderXRequestID))
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if id := rec.Body.String(); len(id) != 32 || rec.Header().Get(echo.HeaderXRequestID) != id {
+		t.Errorf("request without id should get a new one, got %q", id)
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/labstack/echo"
+	corelog "github.com/maps90/go-core/log"
+)
+
+// User stores the acting user returned by lookup in the request context,
+// loggers and audit.WithContext read it with log.User. Install it after the
+// authentication middleware, an empty user is not stored.
+func User(lookup func(echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if user := lookup(c); user != "" {
+				req := c.Request()
+				c.SetRequest(req.WithContext(corelog.WithUser(req.Context(), user)))
+			}
+			return next(c)
+		}
+	}
+}
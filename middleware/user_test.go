@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	corelog "github.com/maps90/go-core/log"
+)
+
+func TestUser(t *testing.T) {
+	e := echo.New()
+	e.Use(User(func(c echo.Context) string {
+		return c.Request().Header.Get("X-User")
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, corelog.User(c.Request().Context()))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-User", "admin")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Body.String() != "admin" {
+		t.Errorf("user should be stored in the request context, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Body.String() != "" {
+		t.Errorf("request without user should have none, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,210 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidCursor is returned when a cursor does not match the columns
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+const timeKey = "$t"
+
+// Column is an ordered column used for keyset pagination, the last column
+// must be unique, e.g. the primary key
+type Column struct {
+	Name string
+	Desc bool
+}
+
+func Asc(name string) Column {
+	return Column{Name: name}
+}
+
+func Desc(name string) Column {
+	return Column{Name: name, Desc: true}
+}
+
+// Cursor holds the column values of the row a page starts after, Prev
+// walks backwards from it
+type Cursor struct {
+	Values []interface{} `json:"v"`
+	Prev   bool          `json:"p,omitempty"`
+}
+
+// Encode returns the cursor as opaque url safe base64
+func (c *Cursor) Encode() string {
+	values := make([]interface{}, len(c.Values))
+	for i, v := range c.Values {
+		// keep time type across the round trip
+		if t, ok := v.(time.Time); ok {
+			v = map[string]string{timeKey: t.Format(time.RFC3339Nano)}
+		}
+		values[i] = v
+	}
+
+	b, _ := json.Marshal(Cursor{Values: values, Prev: c.Prev})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor made by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := new(Cursor)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(c); err != nil || len(c.Values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, v := range c.Values {
+		switch val := v.(type) {
+		case json.Number:
+			if n, err := val.Int64(); err == nil {
+				c.Values[i] = n
+			} else {
+				c.Values[i] = val.String()
+			}
+		case map[string]interface{}:
+			str, _ := val[timeKey].(string)
+			t, err := time.Parse(time.RFC3339Nano, str)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			c.Values[i] = t
+		case []interface{}:
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return c, nil
+}
+
+// Keyset loads one page of db into dest, a pointer to slice, ordered by
+// columns and starting after the request cursor. Unlike Offset the cost
+// does not grow with the page number.
+func Keyset(db *gorm.DB, p *Params, dest interface{}, columns ...Column) (*Page, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("pagination: keyset needs at least one column")
+	}
+
+	backward := p.Cursor != nil && p.Cursor.Prev
+	scope := db.NewScope(dest)
+	query := db
+
+	if p.Cursor != nil {
+		if len(p.Cursor.Values) != len(columns) {
+			return nil, ErrInvalidCursor
+		}
+		where, args := keysetCondition(scope, columns, p.Cursor.Values, backward)
+		query = query.Where(where, args...)
+	}
+
+	for _, col := range columns {
+		dir := " ASC"
+		if col.Desc != backward {
+			dir = " DESC"
+		}
+		query = query.Order(scope.Quote(col.Name) + dir)
+	}
+
+	if err := query.Limit(p.Limit + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	more := trim(dest, p.Limit)
+	if backward {
+		reverse(dest)
+	}
+
+	page := &Page{Data: dest, Meta: Meta{Limit: p.Limit}}
+	page.Links.Self = p.self()
+
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if rows.Len() == 0 {
+		return page, nil
+	}
+
+	first, err := rowValues(db, rows.Index(0), columns)
+	if err != nil {
+		return nil, err
+	}
+	last, err := rowValues(db, rows.Index(rows.Len()-1), columns)
+	if err != nil {
+		return nil, err
+	}
+	if more || backward {
+		next := &Cursor{Values: last}
+		page.Links.Next = p.link(map[string]string{"cursor": next.Encode(), "offset": ""})
+	}
+	if (more && backward) || (!backward && p.Cursor != nil) {
+		prev := &Cursor{Values: first, Prev: true}
+		page.Links.Prev = p.link(map[string]string{"cursor": prev.Encode(), "offset": ""})
+	}
+
+	return page, nil
+}
+
+// keysetCondition builds (a > ?) OR (a = ? AND b > ?) ..., which unlike a
+// row comparison supports mixed sort directions
+func keysetCondition(scope *gorm.Scope, columns []Column, values []interface{}, backward bool) (string, []interface{}) {
+	var (
+		ors  []string
+		args []interface{}
+	)
+
+	for i, col := range columns {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, scope.Quote(columns[j].Name)+" = ?")
+			args = append(args, values[j])
+		}
+
+		op := " > ?"
+		if col.Desc != backward {
+			op = " < ?"
+		}
+		ands = append(ands, scope.Quote(col.Name)+op)
+		args = append(args, values[i])
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// rowValues returns the values of columns in row, a column without a field
+// fails as the cursor would compare with NULL
+func rowValues(db *gorm.DB, row reflect.Value, columns []Column) ([]interface{}, error) {
+	if row.Kind() != reflect.Ptr {
+		row = row.Addr()
+	}
+	scope := db.NewScope(row.Interface())
+
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		name := col.Name
+		if idx := strings.LastIndex(name, "."); idx != -1 {
+			name = name[idx+1:]
+		}
+		field, ok := scope.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("pagination: keyset column %s is not a field of %s", col.Name, scope.GetModelStruct().ModelType)
+		}
+		values[i] = field.Field.Interface()
+	}
+
+	return values, nil
+}
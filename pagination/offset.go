@@ -0,0 +1,64 @@
+package pagination
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Offset loads one page of db into dest, a pointer to slice, using limit
+// and offset. The total is counted only when the request asked for count.
+func Offset(db *gorm.DB, p *Params, dest interface{}) (*Page, error) {
+	offset := p.Offset
+	page := &Page{
+		Data: dest,
+		Meta: Meta{Limit: p.Limit, Offset: &offset},
+	}
+
+	if p.Count {
+		var total int64
+		if err := db.Model(dest).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Meta.Total = &total
+	}
+
+	// fetch one extra row to know whether there is a next page
+	if err := db.Limit(p.Limit + 1).Offset(p.Offset).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	page.Links.Self = p.self()
+	if trim(dest, p.Limit) {
+		page.Links.Next = p.link(map[string]string{"offset": strconv.Itoa(offset + p.Limit)})
+	}
+	if offset > 0 {
+		prev := offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		page.Links.Prev = p.link(map[string]string{"offset": strconv.Itoa(prev)})
+	}
+
+	return page, nil
+}
+
+// trim cuts the slice dest points to down to limit, reporting whether it
+// was longer
+func trim(dest interface{}, limit int) bool {
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	if v.Kind() != reflect.Slice || v.Len() <= limit {
+		return false
+	}
+	v.Set(v.Slice(0, limit))
+	return true
+}
+
+func reverse(dest interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	swap := reflect.Swapper(v.Interface())
+	for i, j := 0, v.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
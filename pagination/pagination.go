@@ -0,0 +1,124 @@
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+var (
+	// DefaultLimit is used when the request has no limit param
+	DefaultLimit = 20
+	// MaxLimit is the largest accepted limit
+	MaxLimit = 100
+	// MaxOffset is the largest accepted offset, deep offsets should use cursors
+	MaxOffset = 10000
+)
+
+// Params are the pagination query params of a request:
+// limit, offset, cursor and count=true
+type Params struct {
+	Limit  int
+	Offset int
+	Count  bool
+	Cursor *Cursor
+
+	url *url.URL
+}
+
+// Page is the standard list response envelope
+type Page struct {
+	Data  interface{} `json:"data"`
+	Meta  Meta        `json:"meta"`
+	Links Links       `json:"links"`
+}
+
+type Meta struct {
+	Limit  int    `json:"limit"`
+	Offset *int   `json:"offset,omitempty"`
+	Total  *int64 `json:"total,omitempty"`
+}
+
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// FromContext parses and bounds checks pagination params of the request,
+// it returns a 400 echo.HTTPError on invalid values
+func FromContext(c echo.Context) (*Params, error) {
+	q := c.QueryParams()
+	p := &Params{Limit: DefaultLimit, url: c.Request().URL}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return nil, badRequest("limit must be between 1 and %d", MaxLimit)
+		}
+		p.Limit = limit
+	}
+
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 || offset > MaxOffset {
+			return nil, badRequest("offset must be between 0 and %d", MaxOffset)
+		}
+		p.Offset = offset
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		if q.Get("offset") != "" {
+			return nil, badRequest("cursor and offset cannot be used together")
+		}
+		cursor, err := DecodeCursor(v)
+		if err != nil {
+			return nil, badRequest("invalid cursor")
+		}
+		p.Cursor = cursor
+	}
+
+	if v := q.Get("count"); v != "" {
+		count, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, badRequest("count must be a boolean")
+		}
+		p.Count = count
+	}
+
+	return p, nil
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(format, args...))
+}
+
+// link returns the request url with params replaced, empty values are removed
+func (p *Params) link(params map[string]string) string {
+	if p.url == nil {
+		return ""
+	}
+
+	u := *p.url
+	q := u.Query()
+	for k, v := range params {
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.RequestURI()
+}
+
+func (p *Params) self() string {
+	if p.url == nil {
+		return ""
+	}
+	return p.url.RequestURI()
+}
@@ -0,0 +1,176 @@
+package pagination
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/maps90/go-core/datasource"
+)
+
+type item struct {
+	ID    int
+	Group string
+}
+
+func newContext(target string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func seed(t *testing.T) *datasource.Sqlite {
+	ds := datasource.NewSqlite(":memory:")
+	db := ds.Write()
+	db.AutoMigrate(&item{})
+	for i, g := range []string{"a", "b", "a", "b", "a"} {
+		if err := db.Create(&item{ID: i + 1, Group: g}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ds
+}
+
+func cursorFrom(t *testing.T, link string) *Params {
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := FromContext(newContext(u.RequestURI()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func ids(items []item) []int {
+	var list []int
+	for _, i := range items {
+		list = append(list, i.ID)
+	}
+	return list
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFromContext(t *testing.T) {
+	p, err := FromContext(newContext("/items"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Limit != DefaultLimit || p.Offset != 0 || p.Count {
+		t.Error("should use defaults")
+	}
+
+	for _, target := range []string{
+		"/items?limit=0",
+		"/items?limit=1000",
+		"/items?offset=-1",
+		"/items?count=maybe",
+		"/items?cursor=not-a-cursor!",
+		"/items?cursor=abc&offset=1",
+	} {
+		if _, err := FromContext(newContext(target)); err == nil {
+			t.Errorf("%s should be rejected", target)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	now := time.Date(2017, 5, 24, 16, 54, 37, 1000, time.UTC)
+	c := &Cursor{Values: []interface{}{now, "a", 42}, Prev: true}
+
+	decoded, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Prev {
+		t.Error("direction should survive round trip")
+	}
+	if tm, ok := decoded.Values[0].(time.Time); !ok || !tm.Equal(now) {
+		t.Errorf("time should survive round trip, got %v", decoded.Values[0])
+	}
+	if decoded.Values[1] != "a" || decoded.Values[2] != int64(42) {
+		t.Errorf("unexpected values %v", decoded.Values)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	ds := seed(t)
+	defer ds.Close()
+
+	p, _ := FromContext(newContext("/items?limit=2&offset=2&count=true"))
+	var items []item
+	page, err := Offset(ds.Read().Order("id"), p, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(ids(items), []int{3, 4}) {
+		t.Errorf("unexpected page %v", ids(items))
+	}
+	if page.Meta.Total == nil || *page.Meta.Total != 5 {
+		t.Error("total should be counted")
+	}
+	if page.Links.Next != "/items?count=true&limit=2&offset=4" || page.Links.Prev != "/items?count=true&limit=2&offset=0" {
+		t.Errorf("unexpected links %+v", page.Links)
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	ds := seed(t)
+	defer ds.Close()
+	columns := []Column{Desc("group"), Asc("id")}
+
+	// b2 b4 a1 a3 a5
+	p, _ := FromContext(newContext("/items?limit=2"))
+	var first []item
+	page, err := Keyset(ds.Read(), p, &first, columns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(ids(first), []int{2, 4}) || page.Links.Prev != "" {
+		t.Fatalf("unexpected first page %v %+v", ids(first), page.Links)
+	}
+
+	var second []item
+	page, err = Keyset(ds.Read(), cursorFrom(t, page.Links.Next), &second, columns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(ids(second), []int{1, 3}) {
+		t.Fatalf("unexpected second page %v", ids(second))
+	}
+
+	var back []item
+	page, err = Keyset(ds.Read(), cursorFrom(t, page.Links.Prev), &back, columns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(ids(back), []int{2, 4}) || page.Links.Next == "" {
+		t.Errorf("prev should return the first page, got %v", ids(back))
+	}
+}
+
+func TestKeysetUnknownColumn(t *testing.T) {
+	ds := seed(t)
+	defer ds.Close()
+
+	p, _ := FromContext(newContext("/items?limit=2"))
+	var rows []item
+	// rowid is a sqlite column without a field
+	if _, err := Keyset(ds.Read(), p, &rows, Asc("rowid")); err == nil {
+		t.Error("column without a field should fail")
+	}
+}
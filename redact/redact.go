@@ -0,0 +1,346 @@
+// Package redact masks sensitive values before they are logged or reported.
+//
+// A Redactor matches values by key name, e.g. password or authorization, and
+// by pattern, e.g. card numbers found inside a message, and masks them with
+// one of the strategies:
+//
+//	Full     [REDACTED]
+//	Partial  ************1111, j***@example.com
+//	Hash     sha256:5e884898da28, equal values get equal hashes
+//
+// Default is used by the log package, middleware.Logger and AppRecover,
+// extend it at startup:
+//
+//	redact.Default.AddKeys(redact.Hash, "nik")
+//	redact.Default.AddPattern(redact.Partial, `\bINV-\d{6}\b`)
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Strategy is how a matched value is masked
+type Strategy int
+
+const (
+	// Full replaces the value with [REDACTED]
+	Full Strategy = iota
+	// Partial keeps the last 4 characters, or the first character and the
+	// domain of an email
+	Partial
+	// Hash replaces the value with a salted sha256 prefix
+	Hash
+)
+
+// Mask is the replacement of the Full strategy
+const Mask = "[REDACTED]"
+
+const redactedPassword = "xxxxx"
+
+// ParseStrategy returns the strategy named full, partial or hash
+func ParseStrategy(name string) (Strategy, error) {
+	switch strings.ToLower(name) {
+	case "full":
+		return Full, nil
+	case "partial":
+		return Partial, nil
+	case "hash":
+		return Hash, nil
+	}
+	return Full, fmt.Errorf("redact: unknown strategy %q", name)
+}
+
+// DefaultKeys are the key names masked by Default
+var DefaultKeys = []string{
+	"password", "passwd", "pwd", "secret", "client_secret",
+	"token", "access_token", "refresh_token", "id_token", "api_key", "apikey",
+	"authorization", "proxy_authorization", "cookie", "set_cookie",
+	"card_number", "cvv", "cvc", "pin", "otp",
+}
+
+// Patterns masked by Default
+var (
+	CardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	EmailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+	// PhonePattern matches indonesian mobile numbers, e.g. 081234567890,
+	// +6281234567890 or 62 812-3456-7890
+	PhonePattern = regexp.MustCompile(`(?:\+62|\b62|\b0)[ -]?8[1-9](?:[ -]?\d){7,10}\b`)
+)
+
+// Default is the Redactor shared by the log package and the middlewares
+var Default = NewDefault()
+
+type pattern struct {
+	re       *regexp.Regexp
+	strategy Strategy
+	// check filters matches, e.g. luhn for card numbers
+	check func(string) bool
+}
+
+// Redactor masks values by key name and by pattern, it is safe for
+// concurrent use
+type Redactor struct {
+	mu       sync.RWMutex
+	keys     map[string]Strategy
+	patterns []pattern
+	salt     string
+}
+
+// New returns a Redactor without rules
+func New() *Redactor {
+	return &Redactor{keys: make(map[string]Strategy)}
+}
+
+// NewDefault returns a Redactor masking DefaultKeys fully and card numbers,
+// emails and phone numbers partially
+func NewDefault() *Redactor {
+	r := New()
+	r.AddKeys(Full, DefaultKeys...)
+	r.addPattern(pattern{re: CardPattern, strategy: Partial, check: luhn})
+	r.addPattern(pattern{re: EmailPattern, strategy: Partial})
+	r.addPattern(pattern{re: PhonePattern, strategy: Partial})
+	return r
+}
+
+// AddKeys masks the values of keys named names with strategy. Names are
+// matched case insensitively with - and _ treated alike, either whole or as
+// a _ separated suffix, so api_key also matches X-Api-Key.
+func (r *Redactor) AddKeys(strategy Strategy, names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		r.keys[normalize(name)] = strategy
+	}
+}
+
+// AddPattern masks the substrings of values and messages matching expr
+func (r *Redactor) AddPattern(strategy Strategy, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("redact: %v", err)
+	}
+
+	r.addPattern(pattern{re: re, strategy: strategy})
+	return nil
+}
+
+func (r *Redactor) addPattern(p pattern) {
+	r.mu.Lock()
+	r.patterns = append(r.patterns, p)
+	r.mu.Unlock()
+}
+
+// SetSalt sets the salt of the Hash strategy
+func (r *Redactor) SetSalt(salt string) {
+	r.mu.Lock()
+	r.salt = salt
+	r.mu.Unlock()
+}
+
+// Key reports whether values of key are masked and how
+func (r *Redactor) Key(key string) (Strategy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key = normalize(key)
+	for {
+		if s, ok := r.keys[key]; ok {
+			return s, true
+		}
+		i := strings.IndexByte(key, '_')
+		if i < 0 {
+			return Full, false
+		}
+		key = key[i+1:]
+	}
+}
+
+// String masks the substrings of s matching a pattern
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	patterns := r.patterns
+	r.mu.RUnlock()
+
+	for _, p := range patterns {
+		p := p
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.check != nil && !p.check(match) {
+				return match
+			}
+			return r.mask(p.strategy, match)
+		})
+	}
+	return s
+}
+
+// Value masks v when key is sensitive, otherwise masks the patterns found in
+// strings, errors and maps of v. Other types are returned as is.
+func (r *Redactor) Value(key string, v interface{}) interface{} {
+	if s, ok := r.Key(key); ok {
+		if v == nil {
+			return nil
+		}
+		return r.mask(s, fmt.Sprint(v))
+	}
+
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case error:
+		if s := r.String(v.Error()); s != v.Error() {
+			return s
+		}
+	case map[string]interface{}:
+		return r.Map(v)
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, val := range v {
+			m[k] = fmt.Sprint(r.Value(k, val))
+		}
+		return m
+	case http.Header:
+		return r.Header(v)
+	case url.Values:
+		m := make(url.Values, len(v))
+		for k, vals := range v {
+			for _, val := range vals {
+				m.Add(k, fmt.Sprint(r.Value(k, val)))
+			}
+		}
+		return m
+	case *url.URL:
+		if v != nil {
+			return r.URL(v.String())
+		}
+	}
+	return v
+}
+
+// Map returns a copy of m with its values masked, see Value
+func (r *Redactor) Map(m map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		masked[k] = r.Value(k, v)
+	}
+	return masked
+}
+
+// Header returns a copy of h with its values masked, see Value
+func (r *Redactor) Header(h http.Header) http.Header {
+	masked := make(http.Header, len(h))
+	for k, vals := range h {
+		for _, v := range vals {
+			masked[k] = append(masked[k], fmt.Sprint(r.Value(k, v)))
+		}
+	}
+	return masked
+}
+
+// URL masks the password and the sensitive query params of raw, which may be
+// a full URL or a request URI. The order of the params is kept.
+func (r *Redactor) URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return r.String(raw)
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedPassword)
+	}
+
+	path, query, fragment := u.EscapedPath(), u.RawQuery, u.Fragment
+	u.Path, u.RawPath, u.RawQuery, u.Fragment = "", "", "", ""
+	s := u.String() + r.String(path)
+	if query != "" {
+		s += "?" + r.query(query)
+	}
+	if fragment != "" {
+		s += "#" + r.String(fragment)
+	}
+	return s
+}
+
+// query masks the params of a raw query
+func (r *Redactor) query(query string) string {
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, err := url.QueryUnescape(parts[0])
+		if err != nil {
+			key = parts[0]
+		}
+		value, err := url.QueryUnescape(parts[1])
+		if err != nil {
+			value = parts[1]
+		}
+
+		if masked := fmt.Sprint(r.Value(key, value)); masked != value {
+			params[i] = parts[0] + "=" + masked
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+func (r *Redactor) mask(s Strategy, value string) string {
+	switch s {
+	case Partial:
+		return partial(value)
+	case Hash:
+		r.mu.RLock()
+		salt := r.salt
+		r.mu.RUnlock()
+
+		sum := sha256.Sum256([]byte(salt + value))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return Mask
+}
+
+func partial(value string) string {
+	if at := strings.LastIndexByte(value, '@'); at > 0 {
+		return value[:1] + "***" + value[at:]
+	}
+
+	runes := []rune(value)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+func normalize(key string) string {
+	return strings.Replace(strings.ToLower(key), "-", "_", -1)
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
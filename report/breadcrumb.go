@@ -0,0 +1,124 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBreadcrumbs is the default number of breadcrumbs kept per scope
+const DefaultBreadcrumbs = 50
+
+// Breadcrumb is something that happened before an event, e.g. a log entry
+type Breadcrumb struct {
+	Time     time.Time              `json:"timestamp"`
+	Category string                 `json:"category,omitempty"`
+	Level    string                 `json:"level,omitempty"`
+	Message  string                 `json:"message"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+type scopeKey struct{}
+
+// scope collects the breadcrumbs and the last event id of a request
+type scope struct {
+	sync.Mutex
+	ring    []Breadcrumb
+	next    int
+	full    bool
+	eventID string
+}
+
+var breadcrumbLimit = struct {
+	sync.RWMutex
+	n int
+}{n: DefaultBreadcrumbs}
+
+// SetBreadcrumbLimit keeps the n most recent breadcrumbs of the scopes
+// created afterwards, 0 disables them
+func SetBreadcrumbLimit(n int) {
+	breadcrumbLimit.Lock()
+	breadcrumbLimit.n = n
+	breadcrumbLimit.Unlock()
+}
+
+// NewScope returns a copy of ctx collecting the breadcrumbs and event ids of
+// a request, so events only carry what happened in their own request. It is
+// installed by middleware.RequestID.
+func NewScope(ctx context.Context) context.Context {
+	breadcrumbLimit.RLock()
+	n := breadcrumbLimit.n
+	breadcrumbLimit.RUnlock()
+
+	return context.WithValue(ctx, scopeKey{}, &scope{ring: make([]Breadcrumb, n)})
+}
+
+// HasScope reports whether ctx carries a scope created by NewScope
+func HasScope(ctx context.Context) bool {
+	return scopeOf(ctx) != nil
+}
+
+func scopeOf(ctx context.Context) *scope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(scopeKey{}).(*scope)
+	return s
+}
+
+// AddBreadcrumb records b in the scope of ctx, dropping the oldest breadcrumb
+// past the limit. Without a scope b is discarded.
+func AddBreadcrumb(ctx context.Context, b Breadcrumb) {
+	s := scopeOf(ctx)
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.ring) == 0 {
+		return
+	}
+	if b.Time.IsZero() {
+		b.Time = time.Now()
+	}
+
+	s.ring[s.next] = b
+	s.next = (s.next + 1) % len(s.ring)
+	s.full = s.full || s.next == 0
+}
+
+// Breadcrumbs returns the breadcrumbs of the scope of ctx, oldest first
+func Breadcrumbs(ctx context.Context) []Breadcrumb {
+	s := scopeOf(ctx)
+	if s == nil {
+		return nil
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.full {
+		return append([]Breadcrumb(nil), s.ring[:s.next]...)
+	}
+	return append(append([]Breadcrumb(nil), s.ring[s.next:]...), s.ring[:s.next]...)
+}
+
+// LastEventID returns the id of the last event reported in the scope of ctx,
+// or empty string
+func LastEventID(ctx context.Context) string {
+	s := scopeOf(ctx)
+	if s == nil {
+		return ""
+	}
+	s.Lock()
+	defer s.Unlock()
+	return s.eventID
+}
+
+func setEventID(ctx context.Context, id string) {
+	if s := scopeOf(ctx); s != nil {
+		s.Lock()
+		s.eventID = id
+		s.Unlock()
+	}
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// limits of Enqueue, events past them are dropped
+const (
+	queueSize   = 100
+	rateLimit   = 10 // events per second
+	sendTimeout = 5 * time.Second
+)
+
+// queued is an event waiting in the queue, or a Flush marker with done
+type queued struct {
+	e    *Event
+	done chan struct{}
+}
+
+var queue = struct {
+	once   sync.Once
+	events chan queued
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}{events: make(chan queued, queueSize)}
+
+// Enqueue sends e in the background with the reporter set by Set and returns
+// its id, also returned by LastEventID for ctx. A single worker sends the
+// events, at most rateLimit per second, events past the rate or a full queue
+// are dropped and Enqueue returns an empty id. Failures go to stderr.
+func Enqueue(ctx context.Context, e *Event) string {
+	if _, ok := Get().(Nop); ok || !allow(time.Now()) {
+		return ""
+	}
+	if e.ID == "" {
+		e.ID = newEventID()
+	}
+	// the scope keeps growing once the event is queued
+	if e.Breadcrumbs == nil {
+		e.Breadcrumbs = Breadcrumbs(ctx)
+	}
+
+	queue.once.Do(func() { go work() })
+	select {
+	case queue.events <- queued{e: e}:
+	default:
+		return ""
+	}
+	setEventID(ctx, e.ID)
+	return e.ID
+}
+
+// Flush waits at most timeout for the enqueued events to be sent
+func Flush(timeout time.Duration) {
+	queue.once.Do(func() { go work() })
+
+	done := make(chan struct{})
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case queue.events <- queued{done: done}:
+	case <-t.C:
+		return
+	}
+	select {
+	case <-done:
+	case <-t.C:
+	}
+}
+
+// allow takes a token of the rate limit, the bucket holds a second of events
+func allow(now time.Time) bool {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.last.IsZero() {
+		queue.tokens = rateLimit
+	} else {
+		queue.tokens += now.Sub(queue.last).Seconds() * rateLimit
+		if queue.tokens > rateLimit {
+			queue.tokens = rateLimit
+		}
+	}
+	queue.last = now
+
+	if queue.tokens < 1 {
+		return false
+	}
+	queue.tokens--
+	return true
+}
+
+func work() {
+	for q := range queue.events {
+		if q.done != nil {
+			close(q.done)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		if _, err := Get().Report(ctx, q.e); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEnqueue(t *testing.T) {
+	if id := Enqueue(context.Background(), &Event{Message: "boom"}); id != "" {
+		t.Errorf("Nop should not enqueue, got %q", id)
+	}
+
+	f := newFakeSentry(t)
+	s, err := NewSentry(f.dsn("public"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(s)
+	defer Set(nil)
+	queue.mu.Lock()
+	queue.last = time.Time{}
+	queue.mu.Unlock()
+
+	ctx := NewScope(context.Background())
+	var ids []string
+	for i := 0; i < rateLimit+5; i++ {
+		if id := Enqueue(ctx, &Event{Message: "boom"}); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	Flush(time.Second)
+
+	if len(ids) != rateLimit {
+		t.Errorf("events past the rate should be dropped, %d enqueued", len(ids))
+	}
+	f.mu.Lock()
+	sent := len(f.events)
+	f.mu.Unlock()
+	if sent != len(ids) {
+		t.Errorf("enqueued events should be sent before Flush returns, %d sent", sent)
+	}
+	if LastEventID(ctx) != ids[len(ids)-1] {
+		t.Errorf("last event id should be stored in the scope, got %q", LastEventID(ctx))
+	}
+
+	if !allow(time.Now().Add(time.Second)) {
+		t.Error("rate limit should refill")
+	}
+}
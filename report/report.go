@@ -0,0 +1,160 @@
+// Package report sends errors to an error tracker, e.g.
+//
+//	s, err := report.NewSentry(dsn)
+//	s.SetEnvironment("production")
+//	s.SetRelease(version)
+//	report.Set(s)
+//
+//	id, err := report.Report(ctx, &report.Event{Error: err, Request: req})
+//
+// The log package reports error entries with Enqueue and records the entries
+// of a request as breadcrumbs of its scope, see NewScope.
+// middleware.AppRecover reports panics. Events are masked with redact.Default
+// before they are sent.
+package report
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels of an Event
+const (
+	LevelDebug   = "debug"
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+	LevelFatal   = "fatal"
+)
+
+// Event is an error to report, Breadcrumbs default to the ones of the scope
+// of the context it is reported with
+type Event struct {
+	// ID is the event id, a new one is assigned when empty
+	ID      string
+	Level   string
+	Message string
+	Error   error
+	Logger  string
+	Time    time.Time
+
+	Request *http.Request
+	User    *User
+	Tags    map[string]string
+	Extra   map[string]interface{}
+
+	// Stack is the stack of the error, oldest frame first, see Stack
+	Stack       []Frame
+	Breadcrumbs []Breadcrumb
+}
+
+// User is who triggered the event
+type User struct {
+	ID       string `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	IP       string `json:"ip_address,omitempty"`
+}
+
+// Frame is a stack frame
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	File     string `json:"filename"`
+	Path     string `json:"abs_path"`
+	Line     int    `json:"lineno"`
+}
+
+// ErrorReporter sends events to an error tracker and returns their id
+type ErrorReporter interface {
+	Report(ctx context.Context, e *Event) (string, error)
+}
+
+// Nop discards events
+type Nop struct{}
+
+func (Nop) Report(context.Context, *Event) (string, error) {
+	return "", nil
+}
+
+var reporter = struct {
+	sync.RWMutex
+	r ErrorReporter
+}{r: Nop{}}
+
+// Set sets the reporter used by Report, nil restores Nop
+func Set(r ErrorReporter) {
+	if r == nil {
+		r = Nop{}
+	}
+
+	reporter.Lock()
+	reporter.r = r
+	reporter.Unlock()
+}
+
+// Get returns the reporter used by Report
+func Get() ErrorReporter {
+	reporter.RLock()
+	defer reporter.RUnlock()
+	return reporter.r
+}
+
+// Report sends e with the reporter set by Set and returns the event id, also
+// returned by LastEventID for ctx
+func Report(ctx context.Context, e *Event) (string, error) {
+	if e.ID == "" {
+		e.ID = newEventID()
+	}
+	id, err := Get().Report(ctx, e)
+	if err == nil && id != "" {
+		setEventID(ctx, id)
+	}
+	return id, err
+}
+
+// Stack returns the frames of the calling goroutine, oldest first, skipping
+// skip frames above the caller of Stack
+func Stack(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+2, pcs)])
+
+	var stack []Frame
+	for {
+		f, more := frames.Next()
+		if f.Function != "runtime.goexit" && f.Function != "" {
+			function, module := f.Function, ""
+			if i := strings.LastIndexByte(f.Function, '/'); i >= 0 {
+				if j := strings.IndexByte(f.Function[i:], '.'); j >= 0 {
+					module, function = f.Function[:i+j], f.Function[i+j+1:]
+				}
+			} else if j := strings.IndexByte(f.Function, '.'); j >= 0 {
+				module, function = f.Function[:j], f.Function[j+1:]
+			}
+
+			file := f.File
+			if i := strings.LastIndexByte(file, '/'); i >= 0 {
+				file = file[i+1:]
+			}
+			stack = append([]Frame{{Function: function, Module: module, File: file, Path: f.File, Line: f.Line}}, stack...)
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// newEventID returns a random 32 hex id
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return hex.EncodeToString(b)
+}
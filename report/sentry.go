@@ -0,0 +1,301 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maps90/go-core/redact"
+)
+
+// sentryClient is the client name sent to Sentry
+const sentryClient = "go-core/1.0"
+
+// Sentry reports events to the store endpoint of a Sentry project
+type Sentry struct {
+	mu          sync.RWMutex
+	endpoint    string
+	key         string
+	secret      string
+	environment string
+	release     string
+	serverName  string
+	tags        map[string]string
+	client      *http.Client
+}
+
+// NewSentry returns a reporter for dsn, e.g.
+// https://public@sentry.example.com/1
+func NewSentry(dsn string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("report: invalid sentry dsn: %v", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("report: sentry dsn has no public key")
+	}
+
+	i := strings.LastIndexByte(u.Path, '/')
+	project := u.Path[i+1:]
+	if project == "" {
+		return nil, fmt.Errorf("report: sentry dsn has no project id")
+	}
+
+	s := &Sentry{
+		key:    u.User.Username(),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	s.secret, _ = u.User.Password()
+	s.serverName, _ = os.Hostname()
+	s.endpoint = fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, u.Path[:i], project)
+
+	return s, nil
+}
+
+// SetEnvironment sets the environment of events, e.g. production
+func (s *Sentry) SetEnvironment(environment string) {
+	s.mu.Lock()
+	s.environment = environment
+	s.mu.Unlock()
+}
+
+// SetRelease sets the release of events, e.g. the version or commit
+func (s *Sentry) SetRelease(release string) {
+	s.mu.Lock()
+	s.release = release
+	s.mu.Unlock()
+}
+
+// SetServerName sets the server name of events, the hostname by default
+func (s *Sentry) SetServerName(name string) {
+	s.mu.Lock()
+	s.serverName = name
+	s.mu.Unlock()
+}
+
+// SetTags sets tags added to all events, the tags of an event win
+func (s *Sentry) SetTags(tags map[string]string) {
+	s.mu.Lock()
+	s.tags = tags
+	s.mu.Unlock()
+}
+
+// SetHTTPClient sets the client events are sent with
+func (s *Sentry) SetHTTPClient(client *http.Client) {
+	s.mu.Lock()
+	s.client = client
+	s.mu.Unlock()
+}
+
+// Report sends e and returns its event id
+func (s *Sentry) Report(ctx context.Context, e *Event) (string, error) {
+	s.mu.RLock()
+	p := s.packet(ctx, e)
+	client := s.client
+	auth := s.auth(p.Timestamp)
+	s.mu.RUnlock()
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("report: encode sentry event: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("report: %v", err)
+	}
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", sentryClient)
+	req.Header.Set("X-Sentry-Auth", auth)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("report: send sentry event: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return "", fmt.Errorf("report: sentry responded %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(ioutil.Discard, res.Body)
+
+	return p.EventID, nil
+}
+
+func (s *Sentry) auth(timestamp string) string {
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_timestamp=%s, sentry_key=%s", sentryClient, timestamp, s.key)
+	if s.secret != "" {
+		auth += ", sentry_secret=" + s.secret
+	}
+	return auth
+}
+
+type sentryPacket struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger,omitempty"`
+	Platform    string                 `json:"platform"`
+	Message     string                 `json:"message,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	User        *User                  `json:"user,omitempty"`
+	Request     *sentryRequest         `json:"request,omitempty"`
+	Exception   *sentryValues          `json:"exception,omitempty"`
+	Breadcrumbs *sentryValues          `json:"breadcrumbs,omitempty"`
+}
+
+type sentryValues struct {
+	Values interface{} `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+type sentryRequest struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+}
+
+// packet builds the payload of e masked with redact.Default, must be called
+// with mu held
+func (s *Sentry) packet(ctx context.Context, e *Event) *sentryPacket {
+	r := redact.Default
+
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	level := e.Level
+	if level == "" {
+		level = LevelError
+	}
+
+	id := e.ID
+	if id == "" {
+		id = newEventID()
+	}
+
+	p := &sentryPacket{
+		EventID:     id,
+		Timestamp:   t.UTC().Format("2006-01-02T15:04:05"),
+		Level:       level,
+		Logger:      e.Logger,
+		Platform:    "go",
+		Message:     r.String(e.Message),
+		Environment: s.environment,
+		Release:     s.release,
+		ServerName:  s.serverName,
+		Extra:       r.Map(e.Extra),
+		User:        e.User,
+	}
+
+	if len(s.tags)+len(e.Tags) > 0 {
+		p.Tags = make(map[string]string, len(s.tags)+len(e.Tags))
+		for k, v := range s.tags {
+			p.Tags[k] = v
+		}
+		for k, v := range e.Tags {
+			p.Tags[k] = v
+		}
+	}
+
+	if e.Error != nil {
+		ex := sentryException{Type: fmt.Sprintf("%T", e.Error), Value: r.String(e.Error.Error())}
+		if len(e.Stack) > 0 {
+			ex.Stacktrace = &sentryStacktrace{Frames: e.Stack}
+		}
+		p.Exception = &sentryValues{Values: []sentryException{ex}}
+		if p.Message == "" {
+			p.Message = ex.Value
+		}
+	}
+
+	if e.Request != nil {
+		p.Request = sentryHTTPRequest(e.Request)
+		if p.User == nil {
+			if ip := remoteIP(e.Request); ip != "" {
+				p.User = &User{IP: ip}
+			}
+		}
+	}
+
+	crumbs := e.Breadcrumbs
+	if crumbs == nil {
+		crumbs = Breadcrumbs(ctx)
+	}
+	if len(crumbs) > 0 {
+		values := make([]Breadcrumb, len(crumbs))
+		for i, b := range crumbs {
+			b.Message = r.String(b.Message)
+			b.Data = r.Map(b.Data)
+			values[i] = b
+		}
+		p.Breadcrumbs = &sentryValues{Values: values}
+	}
+
+	return p
+}
+
+func sentryHTTPRequest(req *http.Request) *sentryRequest {
+	r := redact.Default
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	sr := &sentryRequest{
+		URL:     r.URL(scheme + "://" + req.Host + req.URL.Path),
+		Method:  req.Method,
+		Headers: make(map[string]string, len(req.Header)),
+	}
+	if req.URL.RawQuery != "" {
+		sr.QueryString = strings.TrimPrefix(r.URL("?"+req.URL.RawQuery), "?")
+	}
+	for k, v := range r.Header(req.Header) {
+		sr.Headers[k] = strings.Join(v, ", ")
+	}
+	if ip := remoteIP(req); ip != "" {
+		sr.Env = map[string]string{"REMOTE_ADDR": ip}
+	}
+	return sr
+}
+
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -1,6 +1,13 @@
 package core
 
 import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/labstack/echo"
 	em "github.com/labstack/echo/middleware"
 	"github.com/maps90/go-core/log"
@@ -18,20 +25,34 @@ type RouterSetup interface {
 	SetPort(port string) RouterSetup
 	SetDebug(d bool) RouterSetup
 	SetLoggerName(logName string) RouterSetup
+	SetShutdownTimeout(t time.Duration) RouterSetup
+	OnShutdown(fn func()) RouterSetup
+	SetLogAdmin(token string) RouterSetup
 }
 
+// LogAdminPath is where SetLogAdmin mounts log.LevelHandler
+const LogAdminPath = "/_admin/log/level"
+
 type Route struct {
-	port       string
-	handler    *echo.Echo
-	debug      bool
-	loggerName string
+	port            string
+	handler         *echo.Echo
+	debug           bool
+	loggerName      string
+	shutdownTimeout time.Duration
+	onShutdown      []func()
 }
 
 type RouteFactory func(e Router) (Router, error)
 
 func NewRouter() Router {
+	e := echo.New()
+	e.HTTPErrorHandler = dm.ErrorHandler(e.DefaultHTTPErrorHandler)
+	// every request carries an id, for the logs and the query log
+	e.Use(dm.RequestID())
+
 	return &Route{
-		handler: echo.New(),
+		handler:         e,
+		shutdownTimeout: 10 * time.Second,
 	}
 }
 
@@ -47,13 +68,46 @@ func (r *Route) Setup() RouterSetup {
 	return r
 }
 
+// Run starts the server and blocks until it fails or SIGINT/SIGTERM is
+// received, then drains in-flight requests, runs the shutdown hooks and
+// closes the log files
 func (r *Route) Run() {
 	echo := r.handler
 	echo.Debug = r.debug
 
-	if err := echo.Start(":" + r.port); err != nil {
-		log.New(log.InfoLevelLog, err.Error())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- echo.Start(":" + r.port)
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-errc:
+		if err != nil && err != http.ErrServerClosed {
+			log.New(log.ErrorLevelLog, "router", err.Error())
+		}
+	case <-quit:
+	}
+
+	r.shutdown()
+}
+
+// shutdown stops accepting requests before running the hooks, so handlers
+// still in flight can use what the hooks release
+func (r *Route) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
+	defer cancel()
+	if err := r.handler.Shutdown(ctx); err != nil {
+		log.New(log.ErrorLevelLog, "router", "shutdown: ", err.Error())
+	}
+
+	for i := len(r.onShutdown) - 1; i >= 0; i-- {
+		r.onShutdown[i]()
 	}
+	log.Close()
 }
 
 func (r *Route) SetDebug(d bool) RouterSetup {
@@ -71,6 +125,33 @@ func (r *Route) SetLoggerName(logName string) RouterSetup {
 	return r
 }
 
+// SetShutdownTimeout sets how long Run waits for in-flight requests,
+// defaults to 10s
+func (r *Route) SetShutdownTimeout(t time.Duration) RouterSetup {
+	r.shutdownTimeout = t
+	return r
+}
+
+// OnShutdown registers fn to run once in-flight requests are drained, hooks
+// run in reverse order of registration, e.g. to stop a lock.Elector
+func (r *Route) OnShutdown(fn func()) RouterSetup {
+	r.onShutdown = append(r.onShutdown, fn)
+	return r
+}
+
+// SetLogAdmin mounts log.LevelHandler at LogAdminPath for requests with an
+// "Authorization: Bearer <token>" header, e.g.
+//
+//	curl -H "Authorization: Bearer $TOKEN" -d level=debug -d revert=10m \
+//	    -X PUT localhost:8080/_admin/log/level
+func (r *Route) SetLogAdmin(token string) RouterSetup {
+	auth := dm.BearerAuth(token)
+	r.handler.GET(LogAdminPath, log.LevelHandler, auth)
+	r.handler.PUT(LogAdminPath, log.LevelHandler, auth)
+	r.handler.POST(LogAdminPath, log.LevelHandler, auth)
+	return r
+}
+
 func (r *Route) useMiddleware(echo *echo.Echo) *echo.Echo {
 	echo.Use(em.Recover())
 	echo.Use(em.Gzip())
@@ -137,6 +137,11 @@ func (v *Validation) Name(obj interface{}, key string) *Result {
 	return v.apply(IsName{Match{Regexp: namePattern}, key}, obj)
 }
 
+// Min Test that the obj is greater than min if obj's type is int
+func (v *Validation) Min(obj interface{}, min int, key string) *Result {
+	return v.apply(Min{min, key}, obj)
+}
+
 // Max Test that the obj is less than max if obj's type is int
 func (v *Validation) Max(obj interface{}, max int, key string) *Result {
 	return v.apply(Max{max, key}, obj)